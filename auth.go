@@ -0,0 +1,166 @@
+package goso
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	netUrl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stackExchangeAuthorizeURL = "https://stackoverflow.com/oauth/dialog"
+
+// OAuthConfig configures implicit OAuth flow of Stack Exchange API.
+// See https://api.stackexchange.com/docs/authentication
+type OAuthConfig struct {
+	// ClientID is the ID of registered Stack Exchange app, its OAuth domain should be localhost
+	ClientID string
+	// Scope is the list of requested permissions, e.g. no_expiry
+	Scope []string
+	// AuthorizeURL overrides the address of authorization dialog (default https://stackoverflow.com/oauth/dialog)
+	AuthorizeURL string
+	// Addr is the local address to receive the redirect on (default localhost:0)
+	Addr string
+}
+
+// Token is OAuth access token of Stack Exchange user.
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	Expires     time.Time `json:"expires"`
+}
+
+// Expired reports whether token has expired, tokens with no_expiry scope never expire.
+func (t *Token) Expired() bool {
+	return !t.Expires.IsZero() && time.Now().After(t.Expires)
+}
+
+// redirectPage passes the fragment of redirect URL, not sent by browsers, to the server.
+const redirectPage = `<!DOCTYPE html>
+<html><head><title>goso</title></head>
+<body><p id="status">Completing login...</p>
+<script>
+fetch("/token?" + window.location.hash.substring(1)).then(function (res) {
+  return res.text();
+}).then(function (text) {
+  document.getElementById("status").textContent = text;
+});
+</script>
+</body></html>
+`
+
+// Login obtains access token with implicit OAuth flow. It starts local server
+// to receive the redirect and calls open with the URL of authorization dialog
+// the user should visit. Login waits until user completes the dialog or ctx is done.
+func Login(ctx context.Context, oc *OAuthConfig, open func(url string) error) (*Token, error) {
+	if oc.ClientID == "" {
+		return nil, fmt.Errorf("client ID of Stack Exchange app is required")
+	}
+	l, err := net.Listen("tcp", cmp.Or(oc.Addr, "localhost:0"))
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	stateBytes := make([]byte, 16)
+	if _, err = rand.Read(stateBytes); err != nil {
+		return nil, err
+	}
+	state := hex.EncodeToString(stateBytes)
+	redirectURI := fmt.Sprintf("http://localhost:%d/", l.Addr().(*net.TCPAddr).Port)
+
+	results := make(chan loginResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		// errors are reported in query when the dialog could not be shown
+		if r.URL.Query().Has("error") {
+			token, err := parseRedirect(r.URL.Query(), state)
+			redirectResult(w, results, loginResult{token, err})
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, redirectPage)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		token, err := parseRedirect(r.URL.Query(), state)
+		redirectResult(w, results, loginResult{token, err})
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	params := netUrl.Values{}
+	params.Set("client_id", oc.ClientID)
+	params.Set("redirect_uri", redirectURI)
+	params.Set("state", state)
+	if len(oc.Scope) > 0 {
+		params.Set("scope", strings.Join(oc.Scope, ","))
+	}
+	if err = open(cmp.Or(oc.AuthorizeURL, stackExchangeAuthorizeURL) + "?" + params.Encode()); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-results:
+		return res.token, res.err
+	}
+}
+
+type loginResult struct {
+	token *Token
+	err   error
+}
+
+// errStateMismatch is returned for redirects that do not belong to the current login,
+// such as page reloads or requests from other tabs.
+var errStateMismatch = errors.New("login failed: state of redirect does not match")
+
+// redirectResult answers the redirect and delivers its result of login. Redirects with
+// mismatched state are ignored, so that login keeps waiting for the real one.
+func redirectResult(w http.ResponseWriter, results chan loginResult, res loginResult) {
+	switch {
+	case errors.Is(res.err, errStateMismatch):
+		http.Error(w, "This page does not belong to the current login.", http.StatusBadRequest)
+		return
+	case res.err != nil:
+		http.Error(w, "Login failed, you can close this page.", http.StatusBadRequest)
+	default:
+		fmt.Fprint(w, "Login succeeded, you can close this page.")
+	}
+	sendResult(results, res)
+}
+
+// sendResult delivers the first result of login, the following ones are dropped.
+func sendResult[T any](ch chan T, v T) {
+	select {
+	case ch <- v:
+	default:
+	}
+}
+
+func parseRedirect(params netUrl.Values, state string) (*Token, error) {
+	if params.Get("state") != state {
+		return nil, errStateMismatch
+	}
+	if params.Get("error") != "" {
+		return nil, oauthError(params)
+	}
+	token := &Token{AccessToken: params.Get("access_token")}
+	if token.AccessToken == "" {
+		return nil, errors.New("login failed: redirect has no access token")
+	}
+	if expires, err := strconv.Atoi(params.Get("expires")); err == nil && expires > 0 {
+		token.Expires = time.Now().Add(time.Duration(expires) * time.Second)
+	}
+	return token, nil
+}
+
+func oauthError(params netUrl.Values) error {
+	return fmt.Errorf("login failed: %s", cmp.Or(params.Get("error_description"), params.Get("error")))
+}
@@ -0,0 +1,194 @@
+package goso
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	netUrl "net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAuthorizeServer returns stand-in of authorization dialog that approves
+// every request redirecting with fragment built by fragment.
+func newAuthorizeServer(t *testing.T, fragment func(params netUrl.Values) netUrl.Values) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("client_id") != "1234" || params.Get("scope") != "no_expiry" {
+			http.Error(w, "bad parameter", http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, params.Get("redirect_uri")+"#"+fragment(params).Encode(), http.StatusFound)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// browser follows authorization dialog and runs the script of redirect page.
+func browser(t *testing.T) func(string) error {
+	return func(url string) error {
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		res, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		redirect, err := netUrl.Parse(res.Header.Get("Location"))
+		if err != nil {
+			return err
+		}
+		go func() {
+			page, err := http.Get(redirect.String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body, _ := io.ReadAll(page.Body)
+			page.Body.Close()
+			if !strings.Contains(string(body), "window.location.hash") {
+				t.Error("redirect page does not pass fragment to server")
+			}
+			token, err := http.Get(redirect.ResolveReference(&netUrl.URL{Path: "/token", RawQuery: redirect.Fragment}).String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			token.Body.Close()
+		}()
+		return nil
+	}
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment func(params netUrl.Values) netUrl.Values
+		expires  bool
+		err      string
+	}{
+		{
+			name: "no expiry",
+			fragment: func(params netUrl.Values) netUrl.Values {
+				return netUrl.Values{"access_token": {"secret"}, "state": {params.Get("state")}}
+			},
+		},
+		{
+			name: "expires",
+			fragment: func(params netUrl.Values) netUrl.Values {
+				return netUrl.Values{"access_token": {"secret"}, "expires": {"86400"}, "state": {params.Get("state")}}
+			},
+			expires: true,
+		},
+		{
+			name: "denied",
+			fragment: func(params netUrl.Values) netUrl.Values {
+				return netUrl.Values{"error": {"access_denied"}, "error_description": {"user denied access"}, "state": {params.Get("state")}}
+			},
+			err: "login failed: user denied access",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newAuthorizeServer(t, tt.fragment)
+			oc := &OAuthConfig{ClientID: "1234", Scope: []string{"no_expiry"}, AuthorizeURL: ts.URL}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			token, err := Login(ctx, oc, browser(t))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != "secret" || token.Expires.IsZero() == tt.expires || token.Expired() {
+				t.Fatalf("unexpected token: %+v", token)
+			}
+		})
+	}
+}
+
+func TestLoginStrayRequests(t *testing.T) {
+	ts := newAuthorizeServer(t, func(params netUrl.Values) netUrl.Values {
+		return netUrl.Values{"access_token": {"secret"}, "state": {params.Get("state")}}
+	})
+	oc := &OAuthConfig{ClientID: "1234", Scope: []string{"no_expiry"}, AuthorizeURL: ts.URL}
+	follow := browser(t)
+	// reloads, probes and other tabs come before the real redirect
+	open := func(url string) error {
+		u, err := netUrl.Parse(url)
+		if err != nil {
+			return err
+		}
+		redirectURI := u.Query().Get("redirect_uri")
+		for _, stray := range []string{
+			"token?access_token=forged&state=forged",
+			"token?error=access_denied",
+			"?error=invalid_request&state=forged",
+			"favicon.ico",
+		} {
+			res, err := http.Get(redirectURI + stray)
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+			if res.StatusCode == http.StatusOK {
+				t.Errorf("stray request %q succeeded", stray)
+			}
+		}
+		return follow(url)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	token, err := Login(ctx, oc, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "secret" {
+		t.Fatalf("unexpected token: %+v", token)
+	}
+}
+
+func TestLoginCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := Login(ctx, &OAuthConfig{ClientID: "1234"}, func(string) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestStackExchangeKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("key") != "app-key" || params.Get("access_token") != "secret" {
+			http.Error(w, `{"error_id": 405, "error_name": "key_required", "error_message": "key required"}`, http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, `{"items": []}`)
+	}))
+	t.Cleanup(ts.Close)
+	conf := &Config{
+		Query:              "sort maps",
+		QuestionNum:        1,
+		StackExchangeAPI:   ts.URL,
+		StackExchangeKey:   "app-key",
+		StackExchangeToken: "secret",
+		Client:             ts.Client(),
+	}
+	if _, err := FetchStackExchange(conf); err != nil {
+		t.Fatal(err)
+	}
+	conf.ShowQuestion = true
+	if err := FetchStackOverflow(conf, map[int]*Result{1: {QuestionId: 1}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,444 @@
+package goso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+const (
+	// escape sequences controlling the terminal
+	altScreenOn  string = "\033[?1049h\033[?25l\033[?7l" // alternate screen, hidden cursor, no line wrapping
+	altScreenOff string = "\033[?7h\033[?25h\033[?1049l"
+	cursorHome   string = "\033[H"
+	clearLine    string = "\033[K"
+	clearBelow   string = "\033[J"
+	reverse      string = "\033[7m"
+)
+
+// resizeInterval is how often browser checks the size of terminal.
+const resizeInterval = 250 * time.Millisecond
+
+// escapeKeys maps escape sequences sent by terminals to key names.
+var escapeKeys = map[string]string{
+	"\033[A":  "up",
+	"\033[B":  "down",
+	"\033[C":  "right",
+	"\033[D":  "left",
+	"\033OA":  "up",
+	"\033OB":  "down",
+	"\033OC":  "right",
+	"\033OD":  "left",
+	"\033[5~": "pgup",
+	"\033[6~": "pgdn",
+	"\033[H":  "home",
+	"\033[F":  "end",
+	"\033OH":  "home",
+	"\033OF":  "end",
+	"\033[1~": "home",
+	"\033[4~": "end",
+}
+
+// parseKeys splits input read from terminal in raw mode into key names.
+// Printable keys are named by themselves.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		if b[0] == '\033' {
+			n := 0
+			for seq, name := range escapeKeys {
+				if strings.HasPrefix(string(b), seq) {
+					keys = append(keys, name)
+					n = len(seq)
+					break
+				}
+			}
+			if n == 0 && len(b) > 2 && b[1] == '[' {
+				// skip unknown control sequence up to its final byte
+				n = 2
+				for n < len(b) && (b[n] < 0x40 || b[n] > 0x7e) {
+					n++
+				}
+				n = min(n+1, len(b))
+			} else if n == 0 {
+				keys = append(keys, "esc")
+				n = 1
+			}
+			b = b[n:]
+			continue
+		}
+		r, n := utf8.DecodeRune(b)
+		b = b[n:]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case ' ':
+			keys = append(keys, "space")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x03:
+			keys = append(keys, "ctrl-c")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		default:
+			if r >= ' ' && r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+		}
+	}
+	return keys
+}
+
+// resultBrowser is the state of interactive result browser. It shows the list of
+// questions, and the selected question with its answers when it is open.
+type resultBrowser struct {
+	conf    *Config
+	results []*Result
+	width   int
+	height  int
+	cursor  int // selected question
+	listTop int // first visible question of the list
+	open    bool
+	lines   []string // rendered lines of the open question
+	answers []int    // lines where answers of the open question start
+	top     int      // first visible line of the open question
+}
+
+func newResultBrowser(conf *Config, results []*Result) *resultBrowser {
+	return &resultBrowser{conf: conf, results: results}
+}
+
+// pageSize returns the number of lines available for content, the last line shows status.
+func (b *resultBrowser) pageSize() int {
+	return max(b.height-1, 1)
+}
+
+// resize updates the size of the screen, the open question is rendered anew when width changes.
+func (b *resultBrowser) resize(width, height int) error {
+	widthChanged := width != b.width
+	b.width, b.height = width, height
+	if b.open && widthChanged && b.conf.Width == 0 {
+		return b.render()
+	}
+	b.scroll(0)
+	return nil
+}
+
+// render renders the selected question with its answers.
+func (b *resultBrowser) render() error {
+	conf := *b.conf
+	if conf.Width == 0 {
+		conf.Width = b.width
+	}
+	rd, err := newRenderer(&conf)
+	if err != nil {
+		return err
+	}
+	// code blocks are numbered across all results
+	rd.codeBlock = len(CodeBlocks(&conf, b.results[:b.cursor]))
+	var sb strings.Builder
+	offsets, err := rd.renderResult(b.results[b.cursor], conf.ShowQuestion, &sb)
+	if err != nil {
+		return err
+	}
+	out := sb.String()
+	// headers start with an empty line
+	out = strings.TrimPrefix(out, "\n")
+	b.lines = strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	b.answers = make([]int, len(offsets))
+	for i, offset := range offsets {
+		b.answers[i] = strings.Count(out[:max(offset-1, 0)], "\n") + 1
+	}
+	b.scroll(0)
+	return nil
+}
+
+// scroll moves the open question by n lines keeping the view inside of it.
+func (b *resultBrowser) scroll(n int) {
+	b.top = max(min(b.top+n, len(b.lines)-b.pageSize()), 0)
+	b.cursor = max(min(b.cursor, len(b.results)-1), 0)
+	if b.cursor < b.listTop {
+		b.listTop = b.cursor
+	} else if b.cursor >= b.listTop+b.pageSize() {
+		b.listTop = b.cursor - b.pageSize() + 1
+	}
+}
+
+// handle updates the state on key press, it reports whether the user quits.
+func (b *resultBrowser) handle(key string) (bool, error) {
+	switch key {
+	case "q", "ctrl-c":
+		return true, nil
+	}
+	if !b.open {
+		switch key {
+		case "up", "k":
+			b.cursor--
+		case "down", "j":
+			b.cursor++
+		case "pgup", "b":
+			b.cursor -= b.pageSize()
+		case "pgdn", "space":
+			b.cursor += b.pageSize()
+		case "home", "g":
+			b.cursor = 0
+		case "end", "G":
+			b.cursor = len(b.results) - 1
+		case "enter", "right", "l":
+			b.open = true
+			b.top = 0
+			return false, b.render()
+		case "esc", "left", "h":
+			return true, nil
+		}
+		b.scroll(0)
+		return false, nil
+	}
+	switch key {
+	case "up", "k":
+		b.scroll(-1)
+	case "down", "j":
+		b.scroll(1)
+	case "pgup", "b":
+		b.scroll(-b.pageSize())
+	case "pgdn", "space":
+		b.scroll(b.pageSize())
+	case "home", "g":
+		b.top = 0
+	case "end", "G":
+		b.scroll(len(b.lines))
+	case "n", "tab":
+		if i := slices.IndexFunc(b.answers, func(line int) bool { return line > b.top }); i != -1 {
+			b.top = b.answers[i]
+			b.scroll(0)
+		}
+	case "p", "N":
+		for i := len(b.answers) - 1; i >= 0; i-- {
+			if b.answers[i] < b.top {
+				b.top = b.answers[i]
+				break
+			}
+		}
+	case "left", "h", "esc", "backspace":
+		b.open = false
+		b.lines, b.answers = nil, nil
+	}
+	return false, nil
+}
+
+// listLine returns the line of i-th question in the list.
+func (b *resultBrowser) listLine(i int) string {
+	res := b.results[i]
+	marker := "  "
+	if i == b.cursor {
+		marker = bold + "❯ " + reset
+	}
+	color := yellow
+	if res.UpvoteCount < 0 {
+		color = downvoted
+	}
+	var site string
+	if res.site() != defaultSite {
+		site = " " + siteDomain(res.site())
+	}
+	return fmt.Sprintf("%s%s[%d]%s %s%s%s %s(%d answers)%s%s",
+		marker, color, res.UpvoteCount, reset, questionColor, stripControl(res.Title), reset,
+		lightgray, max(res.AnswerCount, len(res.Answers)), stripControl(site), reset)
+}
+
+// view returns escape sequences drawing the screen.
+func (b *resultBrowser) view() string {
+	var sb strings.Builder
+	sb.WriteString(cursorHome)
+	var status string
+	for i := range b.pageSize() {
+		var line string
+		if b.open {
+			if b.top+i < len(b.lines) {
+				line = b.lines[b.top+i]
+			}
+		} else if b.listTop+i < len(b.results) {
+			line = b.listLine(b.listTop + i)
+		}
+		sb.WriteString(line)
+		sb.WriteString(reset + clearLine + "\r\n")
+	}
+	if b.open {
+		var answer int
+		for i, line := range b.answers {
+			if line <= b.top {
+				answer = i + 1
+			}
+		}
+		status = fmt.Sprintf(" Question %d/%d, answer %d/%d  ↑↓ scroll  space/b page  n/p answer  ← back  q quit",
+			b.cursor+1, len(b.results), answer, len(b.answers))
+	} else {
+		status = fmt.Sprintf(" Question %d/%d  ↑↓ move  enter open  q quit", b.cursor+1, len(b.results))
+	}
+	sb.WriteString(reverse + truncate(status, b.width) + reset + clearLine + clearBelow)
+	return sb.String()
+}
+
+// truncate cuts plain text s to width columns.
+func truncate(s string, width int) string {
+	var w int
+	for i, r := range s {
+		if w += runeWidth(r); w > width {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// inputReader reads terminal input in background, so that it can be waited for along with other events.
+type inputReader struct {
+	in       *os.File
+	input    chan []byte
+	err      chan error
+	done     chan struct{}
+	stopped  chan struct{}
+	deadline bool // whether in supports read deadlines
+}
+
+// readInput starts reading from in until stop is called or reading fails.
+func readInput(in *os.File) *inputReader {
+	r := &inputReader{
+		in:      in,
+		input:   make(chan []byte),
+		err:     make(chan error, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	r.deadline = in.SetReadDeadline(time.Now().Add(resizeInterval)) == nil
+	go r.run()
+	return r
+}
+
+func (r *inputReader) run() {
+	defer close(r.stopped)
+	if r.deadline {
+		defer r.in.SetReadDeadline(time.Time{})
+	}
+	buf := make([]byte, 256)
+	for {
+		if r.deadline {
+			r.in.SetReadDeadline(time.Now().Add(resizeInterval))
+		}
+		n, err := r.in.Read(buf)
+		if r.deadline && errors.Is(err, os.ErrDeadlineExceeded) {
+			select {
+			case <-r.done:
+				return
+			default:
+				continue
+			}
+		}
+		if err != nil {
+			r.err <- err
+			return
+		}
+		if n == 0 {
+			continue
+		}
+		select {
+		case r.input <- slices.Clone(buf[:n]):
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// stop stops reading. Without read deadlines the reader stays blocked in Read
+// until the next input arrives, which it then discards.
+func (r *inputReader) stop() {
+	close(r.done)
+	if r.deadline {
+		<-r.stopped
+	}
+}
+
+// fileFd returns the descriptor of f. Unlike f.Fd it keeps f in non-blocking mode,
+// so that read deadlines still work.
+func fileFd(f *os.File) (int, error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var fd int
+	err = rc.Control(func(s uintptr) { fd = int(s) })
+	return fd, err
+}
+
+// Browse shows results in interactive terminal browser until the user quits or ctx is done.
+// Both in and out should be terminals, in is switched to raw mode while browsing.
+// Input is read with deadlines when in supports them, so nothing reads from in after Browse returns.
+// Otherwise, as with terminals opened in blocking mode, Browse keeps a pending read
+// on in that consumes the next input, in should not be used again until the process exits.
+func Browse(ctx context.Context, conf *Config, results []*Result, in, out *os.File) error {
+	if len(results) == 0 {
+		return nil
+	}
+	fd, err := fileFd(in)
+	if err != nil {
+		return err
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+	io.WriteString(out, altScreenOn)
+	defer io.WriteString(out, altScreenOff)
+
+	reader := readInput(in)
+	defer reader.stop()
+	// terminal size is polled, as resize signal is not portable
+	ticker := time.NewTicker(resizeInterval)
+	defer ticker.Stop()
+	b := newResultBrowser(conf, results)
+	redraw := true
+	for {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			return err
+		}
+		if width != b.width || height != b.height {
+			if err = b.resize(width, height); err != nil {
+				return err
+			}
+			redraw = true
+		}
+		if redraw {
+			if _, err = io.WriteString(out, b.view()); err != nil {
+				return err
+			}
+		}
+		redraw = false
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err = <-reader.err:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+		case data := <-reader.input:
+			for _, key := range parseKeys(data) {
+				quit, err := b.handle(key)
+				if quit || err != nil {
+					return err
+				}
+			}
+			redraw = true
+		}
+	}
+}
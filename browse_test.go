@@ -0,0 +1,143 @@
+package goso
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		keys  []string
+	}{
+		{"jjk", []string{"j", "j", "k"}},
+		{"\033[A\033[B\033OC\033[D", []string{"up", "down", "right", "left"}},
+		{"\033[5~\033[6~\033[H\033[4~", []string{"pgup", "pgdn", "home", "end"}},
+		{"\r \t\x03\x7f", []string{"enter", "space", "tab", "ctrl-c", "backspace"}},
+		{"\033", []string{"esc"}},
+		{"\033[1;5Aq", []string{"q"}},
+		{"ё", []string{"ё"}},
+	}
+	for _, tt := range tests {
+		if keys := parseKeys([]byte(tt.input)); !slices.Equal(keys, tt.keys) {
+			t.Errorf("parseKeys(%q): expected %q, got %q", tt.input, tt.keys, keys)
+		}
+	}
+}
+
+func newTestResults() []*Result {
+	results := make([]*Result, 3)
+	for i := range results {
+		results[i] = &Result{Title: fmt.Sprintf("Question %d", i), QuestionId: i + 1, UpvoteCount: 10 - i}
+		for j := range 2 {
+			results[i].Answers = append(results[i].Answers, &Answer{
+				AnswerId: (i+1)*10 + j,
+				Title:    results[i].Title,
+				Score:    5 - j,
+				Body:     fmt.Sprintf("<p>Answer %d of question %d</p>", j, i) + strings.Repeat("<p>line</p>", 10),
+			})
+		}
+	}
+	return results
+}
+
+func TestBrowser(t *testing.T) {
+	b := newResultBrowser(&Config{Style: "onedark", Lexer: "plaintext"}, newTestResults())
+	if err := b.resize(60, 10); err != nil {
+		t.Fatal(err)
+	}
+	press := func(keys ...string) {
+		t.Helper()
+		for _, key := range keys {
+			if quit, err := b.handle(key); err != nil || quit {
+				t.Fatalf("unexpected result of %q: quit %v, error %v", key, quit, err)
+			}
+		}
+	}
+	view := b.view()
+	if !strings.Contains(view, "❯ "+reset+yellow+"[10]") || !strings.Contains(view, "(2 answers)") {
+		t.Fatalf("first question should be selected:\n%q", view)
+	}
+	press("down", "j", "j", "k")
+	if b.cursor != 1 {
+		t.Fatalf("expected cursor 1, got %d", b.cursor)
+	}
+	press("enter")
+	if !b.open || !strings.Contains(b.view(), "[Question] Question 1") {
+		t.Fatalf("second question should be open:\n%q", b.view())
+	}
+	press("n")
+	if !strings.Contains(b.lines[b.top+1], "[Answer] Question 1") {
+		t.Fatalf("expected header of the first answer, got %q", b.lines[b.top+1])
+	}
+	first := b.top
+	press("n")
+	if b.top <= first || !strings.Contains(b.lines[b.top+1], "[Answer] Question 1") {
+		t.Fatalf("expected header of the second answer at line %d, got %q", b.top+1, b.lines[b.top+1])
+	}
+	if !strings.Contains(b.view(), "answer 2/2") {
+		t.Fatalf("status should show the second answer:\n%q", b.view())
+	}
+	press("p")
+	if b.top != first {
+		t.Fatalf("expected line %d, got %d", first, b.top)
+	}
+	press("G")
+	if b.top != len(b.lines)-b.pageSize() {
+		t.Fatalf("expected last page at line %d, got %d", len(b.lines)-b.pageSize(), b.top)
+	}
+	press("down", "space")
+	if b.top != len(b.lines)-b.pageSize() {
+		t.Fatal("scrolled past the end")
+	}
+	press("h")
+	if b.open || b.cursor != 1 {
+		t.Fatal("expected list with the second question selected")
+	}
+	for _, key := range []string{"q", "esc", "left", "h"} {
+		if quit, _ := b.handle(key); !quit {
+			t.Fatalf("%s should quit from the list", key)
+		}
+	}
+}
+
+func TestReadInputStop(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close(); w.Close() })
+	reader := readInput(r)
+	if !reader.deadline {
+		t.Skip("pipe does not support read deadlines")
+	}
+	fmt.Fprint(w, "q")
+	select {
+	case data := <-reader.input:
+		if string(data) != "q" {
+			t.Fatalf("expected %q, got %q", "q", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("input was not read")
+	}
+	stopped := make(chan struct{})
+	go func() {
+		reader.stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("reader did not stop without input")
+	}
+	// input written after stop stays for the next reader
+	fmt.Fprint(w, "j")
+	buf := make([]byte, 1)
+	if _, err := r.Read(buf); err != nil || buf[0] != 'j' {
+		t.Fatalf("expected %q to be left unread, got %q, %v", "j", buf, err)
+	}
+}
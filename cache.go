@@ -0,0 +1,354 @@
+package goso
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	netUrl "net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cacheExt = ".json"
+
+// ErrCacheMiss is returned in offline mode when response is not cached.
+var ErrCacheMiss = errors.New("response is not cached")
+
+// Cache stores API responses on disk.
+type Cache struct {
+	Dir string
+	// TTL is the time responses are considered fresh
+	TTL time.Duration
+	// MaxSize limits the total size of cached responses in bytes, zero means no limit
+	MaxSize int64
+	// Offline makes the cache serve all responses regardless of their age and never hit the network
+	Offline bool
+
+	mu    sync.Mutex
+	size  int64 // estimated size of cached responses, so that the directory is not scanned on every write
+	sized bool  // whether size has been read from the directory
+}
+
+// CacheStats describes the contents of cache directory.
+type CacheStats struct {
+	Dir     string
+	Entries int
+	Expired int
+	Size    int64
+}
+
+type cacheEntry struct {
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// DefaultCacheDir returns goso directory inside user cache directory
+// ($XDG_CACHE_HOME or ~/.cache on Linux).
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goso"), nil
+}
+
+// cacheKey returns a key of request that does not depend on the order of
+// parameters and question IDs, case and spacing of search terms or credentials.
+func cacheKey(req *http.Request) string {
+	u := req.URL
+	segments := strings.Split(u.Path, "/")
+	for i, seg := range segments {
+		if strings.Contains(seg, ";") {
+			ids := strings.Split(seg, ";")
+			slices.Sort(ids)
+			segments[i] = strings.Join(ids, ";")
+		}
+	}
+	params := u.Query()
+	for _, secret := range []string{"key", "access_token"} {
+		params.Del(secret)
+	}
+	for _, term := range []string{"q", "text", "title"} {
+		if v := params.Get(term); v != "" {
+			params.Set(term, strings.Join(strings.Fields(strings.ToLower(v)), " "))
+		}
+	}
+	key := fmt.Sprintf("%s %s://%s%s?%s", req.Method, u.Scheme, u.Host, strings.Join(segments, "/"), params.Encode())
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+cacheExt)
+}
+
+func (c *Cache) get(key string) (*cacheEntry, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if !c.Offline && c.TTL > 0 && time.Since(info.ModTime()) > c.TTL {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *Cache) put(key string, entry *cacheEntry) error {
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return c.grow(int64(len(data)))
+}
+
+// grow adds n written bytes to the estimated size of cache and prunes it once the estimate
+// exceeds MaxSize. Replaced responses are counted twice, which only makes pruning come earlier.
+func (c *Cache) grow(n int64) error {
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.sized {
+		// the first write reads the size including written response
+		files, err := c.files()
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			c.size += f.size
+		}
+		c.sized = true
+	} else {
+		c.size += n
+	}
+	if c.size <= c.MaxSize {
+		return nil
+	}
+	_, err := c.prune()
+	return err
+}
+
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *Cache) files() ([]cacheFile, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := make([]cacheFile, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != cacheExt {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{
+			path:    filepath.Join(c.Dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+func (c *Cache) expired(f cacheFile) bool {
+	return c.TTL > 0 && time.Since(f.modTime) > c.TTL
+}
+
+// Stats returns the number and size of cached responses.
+func (c *Cache) Stats() (*CacheStats, error) {
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+	stats := &CacheStats{Dir: c.Dir, Entries: len(files)}
+	for _, f := range files {
+		stats.Size += f.size
+		if c.expired(f) {
+			stats.Expired++
+		}
+	}
+	return stats, nil
+}
+
+// Clear removes all cached responses.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size, c.sized = 0, false
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err = os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune removes expired responses and then the oldest ones until
+// the cache fits into MaxSize. It returns the number of removed responses.
+func (c *Cache) Prune() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prune()
+}
+
+// prune is Prune that expects c.mu to be held, it updates the estimated size of cache.
+func (c *Cache) prune() (int, error) {
+	c.size, c.sized = 0, false
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	slices.SortFunc(files, func(a, b cacheFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+	var removed int
+	for _, f := range files {
+		if !c.expired(f) && (c.MaxSize <= 0 || size <= c.MaxSize) {
+			continue
+		}
+		if err = os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		size -= f.size
+		removed++
+	}
+	c.size, c.sized = size, true
+	return removed, nil
+}
+
+// CacheTransport is http.RoundTripper that serves successful GET responses from Cache.
+type CacheTransport struct {
+	Cache *Cache
+	// Transport is used for requests missing in cache, http.DefaultTransport if nil
+	Transport http.RoundTripper
+}
+
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.transport().RoundTrip(req)
+	}
+	key := cacheKey(req)
+	if entry, ok := t.Cache.get(key); ok {
+		return entry.response(req), nil
+	}
+	if t.Cache.Offline {
+		return nil, fmt.Errorf("%w: %s", ErrCacheMiss, redactURL(req.URL))
+	}
+	res, err := t.transport().RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry := &cacheEntry{
+		URL:    redactURL(req.URL),
+		Status: res.StatusCode,
+		Header: res.Header.Clone(),
+		Body:   body,
+	}
+	// failing to cache the response should not fail the request
+	_ = t.Cache.put(key, entry)
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+func (t *CacheTransport) transport() http.RoundTripper {
+	return cmp.Or[http.RoundTripper](t.Transport, http.DefaultTransport)
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	header.Set("X-Goso-Cache", "hit")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// redactURL returns URL without credentials to be stored on disk or shown in errors.
+func redactURL(u *netUrl.URL) string {
+	r := *u
+	params := r.Query()
+	for _, secret := range []string{"key", "access_token"} {
+		if params.Has(secret) {
+			params.Set(secret, "REDACTED")
+		}
+	}
+	r.RawQuery = params.Encode()
+	return r.String()
+}
@@ -0,0 +1,164 @@
+package goso
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCacheClient(t *testing.T, cache *Cache) (*http.Client, *httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		fmt.Fprintf(w, "response %d", hits.Load())
+	}))
+	t.Cleanup(ts.Close)
+	client := &http.Client{Transport: &CacheTransport{Cache: cache, Transport: ts.Client().Transport}}
+	return client, ts, &hits
+}
+
+func get(t *testing.T, client *http.Client, url string) (string, error) {
+	t.Helper()
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func TestCacheTransport(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Hour}
+	client, ts, hits := newCacheClient(t, cache)
+	first, err := get(t, client, ts.URL+"/questions/1;2/answers?site=stackoverflow&q=Sort+Maps&key=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := get(t, client, ts.URL+"/questions/2;1/answers?q=sort++maps&site=stackoverflow&key=other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits.Load() != 1 || first != second {
+		t.Fatalf("expected normalized request to be served from cache, got %d hits", hits.Load())
+	}
+	if _, err = get(t, client, ts.URL+"/questions/3/answers?site=stackoverflow"); err != nil {
+		t.Fatal(err)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("expected different request to miss cache, got %d hits", hits.Load())
+	}
+	files, err := filepath.Glob(filepath.Join(cache.Dir, "*"+cacheExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "secret") {
+			t.Fatalf("credentials are stored in cache file %s", f)
+		}
+	}
+}
+
+func TestCacheExpiration(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+	client, ts, hits := newCacheClient(t, cache)
+	url := ts.URL + "/search?q=maps"
+	if _, err := get(t, client, url); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	files, _ := filepath.Glob(filepath.Join(cache.Dir, "*"+cacheExt))
+	for _, f := range files {
+		if err := os.Chtimes(f, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 1 || stats.Expired != 1 || stats.Size == 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	cache.Offline = true
+	body, err := get(t, client, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "response 1" || hits.Load() != 1 {
+		t.Fatalf("expected offline mode to serve expired response, got %q", body)
+	}
+	if _, err = get(t, client, ts.URL+"/search?q=other"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected cache miss in offline mode, got %v", err)
+	}
+	cache.Offline = false
+	if body, _ = get(t, client, url); body != "response 2" {
+		t.Fatalf("expected expired response to be refetched, got %q", body)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Hour}
+	client, ts, _ := newCacheClient(t, cache)
+	for i := range 5 {
+		if _, err := get(t, client, fmt.Sprintf("%s/search?q=%d", ts.URL, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.MaxSize = stats.Size / 2
+	removed, err := cache.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Fatalf("expected 3 responses to be pruned, got %d", removed)
+	}
+	if err = cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ = cache.Stats(); stats.Entries != 0 {
+		t.Fatalf("expected empty cache, got %d entries", stats.Entries)
+	}
+}
+
+func TestCacheMaxSize(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Hour}
+	client, ts, _ := newCacheClient(t, cache)
+	if _, err := get(t, client, ts.URL+"/search?q=0"); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// responses differ only in numbers of the same length
+	cache.MaxSize = stats.Size * 5 / 2
+	for i := 1; i < 5; i++ {
+		if _, err := get(t, client, fmt.Sprintf("%s/search?q=%d", ts.URL, i)); err != nil {
+			t.Fatal(err)
+		}
+		if stats, _ = cache.Stats(); stats.Size > cache.MaxSize {
+			t.Fatalf("cache of %d bytes exceeds limit of %d bytes", stats.Size, cache.MaxSize)
+		}
+	}
+	if stats.Entries != 2 {
+		t.Fatalf("expected 2 responses to be kept, got %d", stats.Entries)
+	}
+}
@@ -0,0 +1,43 @@
+package goso
+
+import (
+	"encoding/base64"
+	"io"
+)
+
+// CodeBlocks returns raw text of code blocks in results in the order
+// RenderResults shows them, question bodies are included when conf.ShowQuestion is set.
+// Code blocks inside table cells and inline elements are shown inline and are not included.
+func CodeBlocks(conf *Config, results []*Result) []string {
+	var blocks []string
+	for _, res := range results {
+		if conf.ShowQuestion {
+			blocks = appendCodeBlocks(blocks, parseDocument(res.Body))
+		}
+		for _, ans := range res.Answers {
+			blocks = appendCodeBlocks(blocks, parseDocument(ans.Body))
+		}
+	}
+	return blocks
+}
+
+// appendCodeBlocks appends code blocks the renderer numbers, that is the ones found
+// among blocks. Code blocks nested in inline nodes are shown inline like in table cells.
+func appendCodeBlocks(blocks []string, n *node) []string {
+	switch n.kind {
+	case codeBlock:
+		return append(blocks, n.text)
+	case docRoot, blockquote, list, listItem:
+		for _, c := range n.children {
+			blocks = appendCodeBlocks(blocks, c)
+		}
+	}
+	return blocks
+}
+
+// CopyToClipboard writes OSC 52 escape sequence asking the terminal w is connected to
+// to put text on the clipboard. It works over SSH, but the terminal may ignore it.
+func CopyToClipboard(w io.Writer, text string) error {
+	_, err := io.WriteString(w, "\033]52;c;"+base64.StdEncoding.EncodeToString([]byte(text))+"\a")
+	return err
+}
@@ -0,0 +1,64 @@
+package goso
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	results := []*Result{
+		{
+			Body: "<pre><code>question</code></pre>",
+			Answers: []*Answer{
+				{Body: "<p>Use <code>inline</code>:</p><pre><code>if a &lt; b &amp;&amp; c {\n}\n</code></pre>"},
+				{Body: "<ul><li><p>nested</p><pre class=\"lang-py\"><code>print(1)</code></pre></li></ul>"},
+			},
+		},
+		{Answers: []*Answer{{Body: "<blockquote><pre><code>quoted</code></pre></blockquote>"}}},
+	}
+	want := []string{"if a < b && c {\n}", "print(1)", "quoted"}
+	if blocks := CodeBlocks(&Config{}, results); !slices.Equal(blocks, want) {
+		t.Fatalf("expected %q, got %q", want, blocks)
+	}
+	want = append([]string{"question"}, want...)
+	if blocks := CodeBlocks(&Config{ShowQuestion: true}, results); !slices.Equal(blocks, want) {
+		t.Fatalf("expected %q, got %q", want, blocks)
+	}
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	var sb strings.Builder
+	if err := CopyToClipboard(&sb, "fmt.Println(1)"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\033]52;c;Zm10LlByaW50bG4oMSk=\a"; sb.String() != want {
+		t.Fatalf("expected %q, got %q", want, sb.String())
+	}
+}
+
+func TestNumberCodeBlocks(t *testing.T) {
+	conf := &Config{Style: "onedark", Lexer: "plaintext", ShowQuestion: true, NumberCodeBlocks: true}
+	results := []*Result{
+		{Body: "<pre><code>one</code></pre>", Answers: []*Answer{{Body: "<pre><code>two</code></pre>"}}},
+		{Answers: []*Answer{{Body: "<table><tr><td><pre><code>cell</code></pre></td></tr></table><pre><code>three</code></pre>"}}},
+		{Answers: []*Answer{{Body: "<p>x</p><strong><pre><code>inline</code></pre></strong><pre><code>four</code></pre>"}}},
+	}
+	out, err := RenderResults(conf, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = ansiPattern.ReplaceAllString(out, "")
+	for i, code := range CodeBlocks(conf, results) {
+		if label := fmt.Sprintf("[code %d]\n%s", i+1, code); !strings.Contains(out, label) {
+			t.Fatalf("expected %q in output:\n%s", label, out)
+		}
+	}
+	if blocks := CodeBlocks(conf, results); !slices.Equal(blocks, []string{"one", "two", "three", "four"}) {
+		t.Fatalf("expected only numbered code blocks, got %q", blocks)
+	}
+	if strings.Contains(out, "[code 5]") {
+		t.Fatal("code blocks inside table and inline elements should not be numbered")
+	}
+}
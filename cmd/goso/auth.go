@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/shadowy-pycoder/goso"
+)
+
+var authCommands = []string{"login", "logout"}
+
+// tokenPath returns the file access token is stored in.
+func tokenPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goso", "token.json"), nil
+}
+
+// loadToken returns access token from `GOSO_STACKEXCHANGE_TOKEN` or the one saved by `goso auth login`.
+func loadToken() (string, error) {
+	if token, set := os.LookupEnv("GOSO_STACKEXCHANGE_TOKEN"); set {
+		return token, nil
+	}
+	path, err := tokenPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var token goso.Token
+	if err = json.Unmarshal(data, &token); err != nil {
+		return "", fmt.Errorf("failed parsing %s: %w", path, err)
+	}
+	if token.Expired() {
+		fmt.Fprintf(os.Stderr, "%s: warning: access token has expired, run `%s auth login` to get a new one\n", app, app)
+		return "", nil
+	}
+	return token.AccessToken, nil
+}
+
+func authCmd(ctx context.Context, command string) error {
+	path, err := tokenPath()
+	if err != nil {
+		return err
+	}
+	if command == "logout" {
+		if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		fmt.Println("Access token removed")
+		return nil
+	}
+	clientID := os.Getenv("GOSO_STACKEXCHANGE_CLIENT_ID")
+	if clientID == "" {
+		return fmt.Errorf("login requires client ID of Stack Exchange app, please check if `GOSO_STACKEXCHANGE_CLIENT_ID` is set correctly")
+	}
+	oc := &goso.OAuthConfig{ClientID: clientID, Scope: []string{"no_expiry"}}
+	token, err := goso.Login(ctx, oc, func(url string) error {
+		fmt.Fprintf(os.Stderr, "Open the following URL in your browser to log in:\n\n%s\n\n", url)
+		// the URL is already printed, failing to open browser is not an error
+		_ = openBrowser(url)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("Logged in, access token saved to %s\n", path)
+	return nil
+}
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
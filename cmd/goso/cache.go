@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shadowy-pycoder/goso"
+)
+
+const (
+	cacheTTLDefault  = 24 * time.Hour
+	cacheSizeDefault = 50 // megabytes
+)
+
+var cacheCommands = []string{"stats", "clear", "prune"}
+
+// cacheLimits returns the cache TTL and size in megabytes set by `GOSO_CACHE_TTL` and `GOSO_CACHE_SIZE`.
+// Defaults are returned along with the error, so that flags can be set up even when cache is disabled.
+func cacheLimits() (time.Duration, int64, error) {
+	ttl, size := cacheTTLDefault, int64(cacheSizeDefault)
+	if v, set := os.LookupEnv("GOSO_CACHE_TTL"); set {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return ttl, size, fmt.Errorf("-cache-ttl should be a non-negative duration, please check if `GOSO_CACHE_TTL` is set correctly")
+		}
+		ttl = d
+	}
+	if v, set := os.LookupEnv("GOSO_CACHE_SIZE"); set {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || mb < 0 {
+			return ttl, size, fmt.Errorf("-cache-size should be a non-negative number, please check if `GOSO_CACHE_SIZE` is set correctly")
+		}
+		size = mb
+	}
+	return ttl, size, nil
+}
+
+// newCache returns cache in `GOSO_CACHE_DIR` or the default cache directory.
+func newCache(ttl time.Duration, sizeMB int64) (*goso.Cache, error) {
+	if ttl < 0 {
+		return nil, fmt.Errorf("-cache-ttl should be a non-negative duration")
+	}
+	if sizeMB < 0 {
+		return nil, fmt.Errorf("-cache-size should be a non-negative number")
+	}
+	cache := &goso.Cache{TTL: ttl, MaxSize: sizeMB << 20}
+	dir, set := os.LookupEnv("GOSO_CACHE_DIR")
+	if set {
+		cache.Dir = dir
+		return cache, nil
+	}
+	var err error
+	cache.Dir, err = goso.DefaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+func cacheCmd(command string) error {
+	ttl, size, err := cacheLimits()
+	if err != nil {
+		return err
+	}
+	cache, err := newCache(ttl, size)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "stats":
+		stats, err := cache.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Directory: %s\nEntries:   %d (%d expired)\nSize:      %.2f MB of %d MB\nTTL:       %s\n",
+			stats.Dir, stats.Entries, stats.Expired, float64(stats.Size)/(1<<20), cache.MaxSize>>20, cache.TTL)
+	case "clear":
+		if err = cache.Clear(); err != nil {
+			return err
+		}
+		fmt.Println("Cache cleared")
+	case "prune":
+		removed, err := cache.Prune()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d entries\n", removed)
+	}
+	return nil
+}
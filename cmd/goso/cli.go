@@ -1,22 +1,27 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"flag"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/shadowy-pycoder/goso"
+	"golang.org/x/term"
 )
 
 const (
 	app                  string = "goso"
 	questionCountDefault int    = 10
+	questionCountMax     int    = 100
 	answerCountDefault   int    = 3
+	retriesDefault       int    = 3
 )
 
 const usagePrefix string = `                                                                  
@@ -30,17 +35,25 @@ Y8b d88P  Stack Overlow CLI Tool by shadowy-pycoder
  "Y88P"   GitHub: https://github.com/shadowy-pycoder/goso                        
                                                                                                                                                                                               
 Usage: goso [OPTIONS] QUERY
+       goso cache stats|clear|prune
+       goso quota
+       goso auth login|logout
 Options:
   -h    Show this help message and exit.
 `
 
-func root(args []string) error {
-
+func root(ctx context.Context, args []string) error {
+	if len(args) == 2 && args[0] == "cache" && slices.Contains(cacheCommands, args[1]) {
+		return cacheCmd(args[1])
+	}
+	if len(args) == 1 && args[0] == "quota" {
+		return quotaCmd()
+	}
+	if len(args) == 2 && args[0] == "auth" && slices.Contains(authCommands, args[1]) {
+		return authCmd(ctx, args[1])
+	}
 	conf := &goso.Config{
 		Client: &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
 			Timeout: time.Duration(10) * time.Second,
 		},
 	}
@@ -50,10 +63,7 @@ func root(args []string) error {
 		set        bool
 		err        error
 	)
-	lex, set = os.LookupEnv("GOSO_LEXER")
-	if !set {
-		lex = "bash"
-	}
+	lex = os.Getenv("GOSO_LEXER")
 	style, set = os.LookupEnv("GOSO_STYLE")
 	if !set {
 		style = "onedark"
@@ -64,10 +74,10 @@ func root(args []string) error {
 	} else {
 		qn, err = strconv.Atoi(q)
 		if err != nil {
-			return fmt.Errorf("-q should be within [min=1, max=10], please check if `GOSO_QUESTIONS` is set correctly")
+			return fmt.Errorf("-q should be within [min=1, max=%d], please check if `GOSO_QUESTIONS` is set correctly", questionCountMax)
 		}
-		if qn < 1 || qn > 10 {
-			return fmt.Errorf("-q should be within [min=1, max=10], please check if `GOSO_QUESTIONS` is set correctly")
+		if qn < 1 || qn > questionCountMax {
+			return fmt.Errorf("-q should be within [min=1, max=%d], please check if `GOSO_QUESTIONS` is set correctly", questionCountMax)
 		}
 	}
 	a, set := os.LookupEnv("GOSO_ANSWERS")
@@ -96,10 +106,66 @@ func root(args []string) error {
 			return fmt.Errorf("show question should be within [min=0, max=1], please check if `GOSO_SHOW_QUESTIONS` is set correctly")
 		}
 	}
+	var width int
+	w, set := os.LookupEnv("GOSO_WIDTH")
+	if set {
+		width, err = strconv.Atoi(w)
+		if err != nil || width < 0 {
+			return fmt.Errorf("-w should be a non-negative number, please check if `GOSO_WIDTH` is set correctly")
+		}
+	}
+	retries := retriesDefault
+	r, set := os.LookupEnv("GOSO_RETRIES")
+	if set {
+		retries, err = strconv.Atoi(r)
+		if err != nil || retries < 1 {
+			return fmt.Errorf("-retries should be a positive number, please check if `GOSO_RETRIES` is set correctly")
+		}
+	}
+	p, set := os.LookupEnv("GOSO_PAGES")
+	if set {
+		conf.GooglePages, err = strconv.Atoi(p)
+		if err != nil || conf.GooglePages < 0 {
+			return fmt.Errorf("-pages should be a non-negative number, please check if `GOSO_PAGES` is set correctly")
+		}
+	}
+	// invalid cache settings matter only when cache is enabled
+	cacheTTL, cacheSize, cacheErr := cacheLimits()
+	conf.Quota, err = newQuotaTracker()
+	if err != nil {
+		return err
+	}
 	flags := flag.NewFlagSet(app, flag.ExitOnError)
-	flags.StringVar(&conf.Lexer, "l", lex, "The name of Chroma lexer. See https://github.com/alecthomas/chroma/tree/master/lexers/embedded")
+	flags.StringVar(&conf.Lexer, "l", lex, "The name of Chroma lexer for all code blocks (default: detected for each block). See https://github.com/alecthomas/chroma/tree/master/lexers/embedded")
 	flags.StringVar(&conf.Style, "s", style, "The name of Chroma style. See https://xyproto.github.io/splash/docs/")
-	qNum := flags.Int("q", qn, "The number of questions [min=1, max=10]")
+	engine := flags.String("e", os.Getenv("GOSO_ENGINE"),
+		fmt.Sprintf("The name of search engine %v (default: first configured)", goso.Searchers()))
+	site, set := os.LookupEnv("GOSO_SITE")
+	if !set {
+		site = "stackoverflow"
+	}
+	flags.StringVar(&site, "site", site, "Comma separated list of Stack Exchange sites to search, e.g. superuser,askubuntu,unix")
+	format, set := os.LookupEnv("GOSO_FORMAT")
+	if !set {
+		format = "text"
+	}
+	flags.StringVar(&format, "format", format, "Output format [text, json, ndjson, markdown]")
+	flags.IntVar(&conf.Width, "w", width, "The number of columns to wrap text to (default: terminal width)")
+	tc := &goso.TransportConfig{CAFile: os.Getenv("GOSO_CA_FILE"), Proxy: os.Getenv("GOSO_PROXY")}
+	flags.StringVar(&tc.CAFile, "ca-file", tc.CAFile, "PEM file with additional trusted CA certificates")
+	flags.StringVar(&tc.Proxy, "proxy", tc.Proxy, "Proxy URL [http://, https://, socks5://] (default: HTTPS_PROXY and HTTP_PROXY variables)")
+	flags.BoolVar(&tc.Insecure, "insecure", false, "Disable TLS certificate verification (not recommended)")
+	flags.IntVar(&retries, "retries", retries, "The number of attempts of each API request")
+	offline := flags.Bool("offline", false, "Answer only from cache without network requests")
+	noCache := flags.Bool("no-cache", false, "Disable response cache")
+	flags.DurationVar(&cacheTTL, "cache-ttl", cacheTTL, "The time cached responses are considered fresh")
+	flags.Int64Var(&cacheSize, "cache-size", cacheSize, "The maximum size of response cache in megabytes, 0 means no limit")
+	flags.IntVar(&conf.GooglePages, "pages", conf.GooglePages, "The maximum number of Google result pages of 10 items to fetch, each page uses one query of the daily quota (default: as many as -q requires)")
+	debug := flags.Bool("debug", false, "Print debug messages to stderr")
+	noPager := flags.Bool("no-pager", false, "Print results without pager (default: $GOSO_PAGER, $PAGER or less -FRX when output does not fit on the screen)")
+	copyBlock := flags.Int("copy", 0, "Copy N-th code block of the results to clipboard with OSC 52 escape sequence")
+	interactive := flags.Bool("i", false, "Browse results interactively, plain output is printed when stdout is not a terminal")
+	qNum := flags.Int("q", qn, fmt.Sprintf("The number of questions [min=1, max=%d]", questionCountMax))
 	aNum := flags.Int("a", an, "The number of answers for each result [min=1, max=10]")
 	flags.BoolFunc("v", "print version", func(flagValue string) error {
 		fmt.Println(app, goso.Version)
@@ -115,15 +181,60 @@ func root(args []string) error {
 	if err := flags.Parse(args); err != nil {
 		return err
 	}
-	if *qNum < 1 || *qNum > 10 {
-		return fmt.Errorf("-q should be within [min=1, max=10]")
+	if *qNum < 1 || *qNum > questionCountMax {
+		return fmt.Errorf("-q should be within [min=1, max=%d]", questionCountMax)
 	}
 	conf.QuestionNum = *qNum
 	if *aNum < 1 || *aNum > 10 {
 		return fmt.Errorf("-a should be within [min=1, max=10]")
 	}
 	conf.AnswerNum = *aNum
-	var fetchFunc func(*goso.Config, map[int]*goso.Result) error
+	if conf.Width < 0 {
+		return fmt.Errorf("-w should be a non-negative number")
+	}
+	if !slices.Contains([]string{"text", "json", "ndjson", "markdown"}, format) {
+		return fmt.Errorf("-format should be one of [text, json, ndjson, markdown]")
+	}
+	if *copyBlock < 0 {
+		return fmt.Errorf("-copy should be a non-negative number")
+	}
+	conf.NumberCodeBlocks = *copyBlock > 0 || *interactive
+	if *interactive && format != "text" {
+		return fmt.Errorf("-i requires text format")
+	}
+	conf.Sites = strings.Split(site, ",")
+	if *debug {
+		conf.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	if conf.GooglePages < 0 {
+		return fmt.Errorf("-pages should be a non-negative number")
+	}
+	if retries < 1 {
+		return fmt.Errorf("-retries should be a positive number")
+	}
+	conf.Retry = &goso.RetryPolicy{Attempts: retries, Jitter: 0.5}
+	if *offline && *noCache {
+		return fmt.Errorf("-offline requires cache, remove -no-cache")
+	}
+	if tc.Insecure {
+		fmt.Fprintf(os.Stderr, "%s: warning: TLS certificate verification is disabled, connections are not secure\n", app)
+	}
+	transport, err := goso.NewTransport(tc)
+	if err != nil {
+		return err
+	}
+	conf.Client.Transport = transport
+	if !*noCache {
+		if cacheErr != nil {
+			return cacheErr
+		}
+		cache, err := newCache(cacheTTL, cacheSize)
+		if err != nil {
+			return err
+		}
+		cache.Offline = *offline
+		conf.Client.Transport = &goso.CacheTransport{Cache: cache, Transport: conf.Client.Transport}
+	}
 	osHost, hostSet := os.LookupEnv("GOSO_OS_HOST")
 	osPort, portSet := os.LookupEnv("GOSO_OS_PORT")
 	if hostSet && portSet {
@@ -132,28 +243,53 @@ func root(args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed parsing `GOSO_OS_PORT`")
 		}
-		fetchFunc = goso.FetchOpenSerp
-	} else {
-		apiKey, set := os.LookupEnv("GOSO_API_KEY")
-		if !set {
-			return fmt.Errorf("`GOSO_API_KEY` is not set")
-		}
-		conf.ApiKey = apiKey
-		se, set := os.LookupEnv("GOSO_SE")
-		if !set {
-			return fmt.Errorf("`GOSO_SE` is not set")
-		}
-		conf.SearchEngine = se
-		fetchFunc = goso.FetchGoogle
+	}
+	conf.StackExchangeKey = os.Getenv("GOSO_STACKEXCHANGE_KEY")
+	conf.StackExchangeToken, err = loadToken()
+	if err != nil {
+		return err
+	}
+	if conf.StackExchangeToken != "" && conf.StackExchangeKey == "" {
+		// Stack Exchange API rejects access token sent without app key
+		fmt.Fprintf(os.Stderr, "%s: warning: access token is not used as `GOSO_STACKEXCHANGE_KEY` is not set\n", app)
+		conf.StackExchangeToken = ""
+	}
+	conf.ApiKey = os.Getenv("GOSO_API_KEY")
+	conf.SearchEngine = os.Getenv("GOSO_SE")
+	searcher, err := goso.SelectSearcher(*engine, conf)
+	if err != nil {
+		return err
 	}
 	conf.Query = strings.Join(flags.Args(), " ")
 	if conf.Query == "" {
 		return fmt.Errorf("query is empty")
 	}
-	answers, err := goso.GetAnswers(conf, fetchFunc, goso.FetchStackOverflow)
+	results, err := goso.GetResultsContext(ctx, conf, searcher, goso.FetchStackOverflowContext)
+	if q, qErr := conf.Quota.Usage(); qErr == nil {
+		warnQuota(q)
+	}
+	if err != nil {
+		return err
+	}
+	if *copyBlock > 0 {
+		if err = copyCode(conf, results, *copyBlock); err != nil {
+			return err
+		}
+	}
+	switch format {
+	case "json":
+		return goso.RenderJSON(os.Stdout, conf, results)
+	case "ndjson":
+		return goso.RenderNDJSON(os.Stdout, conf, results)
+	case "markdown":
+		return goso.RenderMarkdown(os.Stdout, conf, results)
+	}
+	if *interactive && term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		return goso.Browse(ctx, conf, results, os.Stdin, os.Stdout)
+	}
+	answers, err := goso.RenderResultsContext(ctx, conf, results)
 	if err != nil {
 		return err
 	}
-	fmt.Println(answers)
-	return nil
+	return printPaged(answers, *noPager)
 }
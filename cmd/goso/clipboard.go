@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/shadowy-pycoder/goso"
+	"golang.org/x/term"
+)
+
+// copyCode puts n-th code block of results on the clipboard of the terminal.
+func copyCode(conf *goso.Config, results []*goso.Result, n int) error {
+	blocks := goso.CodeBlocks(conf, results)
+	if len(blocks) == 0 {
+		return fmt.Errorf("-copy: results have no code blocks")
+	}
+	if n > len(blocks) {
+		return fmt.Errorf("-copy should be within [min=1, max=%d]", len(blocks))
+	}
+	// the escape sequence should reach the terminal even when results are redirected
+	tty := os.Stdout
+	if !term.IsTerminal(int(tty.Fd())) {
+		tty = os.Stderr
+		if !term.IsTerminal(int(tty.Fd())) {
+			return fmt.Errorf("-copy requires terminal")
+		}
+	}
+	if err := goso.CopyToClipboard(tty, blocks[n-1]); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: copied code block %d of %d to clipboard\n", app, n, len(blocks))
+	return nil
+}
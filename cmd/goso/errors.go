@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/shadowy-pycoder/goso"
+)
+
+// errorHint suggests what user can do about API error.
+func errorHint(err error) string {
+	var apiErr *goso.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	switch apiErr.Name {
+	case "throttle_violation":
+		return "Stack Exchange API limits the number of requests, try again later or answer from cache with -offline"
+	case "key_required", "access_denied":
+		return "Stack Exchange API rejected the request, please check if `GOSO_STACKEXCHANGE_KEY` is set correctly"
+	case "access_token_required", "invalid_access_token", "access_token_compromised":
+		return "Stack Exchange access token is not valid, run `goso auth login` to get a new one"
+	case "internal_error", "temporarily_unavailable":
+		return "Stack Exchange API is temporarily unavailable, try again later"
+	case "keyInvalid", "API_KEY_INVALID":
+		return "Google API key is invalid, please check if `GOSO_API_KEY` is set correctly"
+	case "dailyLimitExceeded", "rateLimitExceeded", "quotaExceeded", "userRateLimitExceeded", "RESOURCE_EXHAUSTED":
+		return "Google Custom Search quota is exhausted, try again tomorrow or use another engine with -e stackexchange"
+	case "accessNotConfigured", "PERMISSION_DENIED":
+		return "enable Custom Search API for the project of your API key in Google Cloud console"
+	case "invalid", "INVALID_ARGUMENT":
+		if apiErr.API == "Google API" {
+			return "please check if `GOSO_API_KEY` and `GOSO_SE` are set correctly"
+		}
+	}
+	if apiErr.Throttled() {
+		return "too many requests, try again later"
+	}
+	return ""
+}
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 )
 
 func main() {
-	if err := root(os.Args[1:]); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := root(ctx, os.Args[1:])
+	stop()
+	if err != nil {
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "%s: %v\n%s: %s\n", app, err, app, hint)
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "%s: %v (type '%s -h' for help)\n", app, err, app)
 		os.Exit(2)
 	}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	"github.com/shadowy-pycoder/goso"
+	"golang.org/x/term"
+)
+
+const (
+	pagerDefault string = "less -FRX"
+	// lessDefault makes less quit when output fits on the screen, pass colors and keep the screen
+	lessDefault string = "FRX"
+)
+
+// pagerCommand returns the pager set by `GOSO_PAGER` or `PAGER`, less by default.
+// Empty `GOSO_PAGER` disables paging.
+func pagerCommand() ([]string, error) {
+	pager, set := os.LookupEnv("GOSO_PAGER")
+	if !set {
+		pager = os.Getenv("PAGER")
+		if pager == "" {
+			pager = pagerDefault
+		}
+	}
+	return splitArgs(pager)
+}
+
+// splitArgs splits command line into arguments the way POSIX shell does,
+// supporting single and double quotes and backslash escapes.
+func splitArgs(s string) ([]string, error) {
+	var (
+		args  []string
+		arg   strings.Builder
+		inArg bool
+		quote rune
+	)
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\' && (quote == 0 || i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1])):
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("unterminated escape in %q", s)
+			}
+			i++
+			arg.WriteRune(runes[i])
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// printPaged prints output through the pager when stdout is a terminal and
+// output does not fit on the screen, otherwise it prints output as is.
+func printPaged(output string, noPager bool) error {
+	if noPager || !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Println(output)
+		return nil
+	}
+	pager, err := pagerCommand()
+	if err != nil {
+		return fmt.Errorf("failed parsing pager command: %w, please check if `GOSO_PAGER` and `PAGER` are set correctly", err)
+	}
+	columns, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if len(pager) == 0 || err != nil || columns <= 0 || goso.ScreenLines(output, columns) < height {
+		fmt.Println(output)
+		return nil
+	}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = strings.NewReader(output + "\n")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// like git, make less show colors when user has not configured it
+	if _, set := os.LookupEnv("LESS"); !set {
+		cmd.Env = append(os.Environ(), "LESS="+lessDefault)
+	}
+	err = cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		// missing pager should not hide the results
+		fmt.Fprintf(os.Stderr, "%s: warning: pager %q not found, set `GOSO_PAGER` or use -no-pager\n", app, pager[0])
+		fmt.Println(output)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed running pager %q: %w", pager[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+		err  bool
+	}{
+		{s: "less -FRX", want: []string{"less", "-FRX"}},
+		{s: "  less\t-R  ", want: []string{"less", "-R"}},
+		{s: "", want: nil},
+		{s: `"/opt/my pager/bin/pager" -x`, want: []string{"/opt/my pager/bin/pager", "-x"}},
+		{s: `less '--prompt=a "b" c'`, want: []string{"less", `--prompt=a "b" c`}},
+		{s: `less --prompt="it's"`, want: []string{"less", "--prompt=it's"}},
+		{s: `my\ pager -x`, want: []string{"my pager", "-x"}},
+		{s: `a\\b "c\"d" "e\f" 'g\h'`, want: []string{`a\b`, `c"d`, `e\f`, `g\h`}},
+		{s: `pager "" ''`, want: []string{"pager", "", ""}},
+		{s: `less "unterminated`, err: true},
+		{s: `less 'unterminated`, err: true},
+		{s: `less \`, err: true},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.s)
+		if tt.err {
+			if err == nil {
+				t.Errorf("splitArgs(%q) = %q, expected error", tt.s, got)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, %v, want %q", tt.s, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/shadowy-pycoder/goso"
+)
+
+// newQuotaTracker returns quota tracker configured with `GOSO_QUOTA_FILE` and `GOSO_GOOGLE_QUOTA`.
+func newQuotaTracker() (*goso.QuotaTracker, error) {
+	var err error
+	tracker := &goso.QuotaTracker{}
+	path, set := os.LookupEnv("GOSO_QUOTA_FILE")
+	if set {
+		tracker.Path = path
+	} else {
+		tracker.Path, err = goso.DefaultQuotaPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	limit, set := os.LookupEnv("GOSO_GOOGLE_QUOTA")
+	if set {
+		tracker.GoogleLimit, err = strconv.Atoi(limit)
+		if err != nil || tracker.GoogleLimit < 1 {
+			return nil, fmt.Errorf("daily Google quota should be a positive number, please check if `GOSO_GOOGLE_QUOTA` is set correctly")
+		}
+	}
+	return tracker, nil
+}
+
+func quotaCmd() error {
+	tracker, err := newQuotaTracker()
+	if err != nil {
+		return err
+	}
+	q, err := tracker.Usage()
+	if err != nil {
+		return err
+	}
+	se := q.StackExchange
+	if se.Max > 0 {
+		fmt.Printf("Stack Exchange API (%s UTC):      %d of %d requests left, %d made by goso\n",
+			se.Date, se.Remaining, se.Max, se.Requests)
+	} else {
+		fmt.Printf("Stack Exchange API (%s UTC):      no requests today\n", se.Date)
+	}
+	g := q.Google
+	fmt.Printf("Google Custom Search API (%s PT): %d of %d queries left\n", g.Date, max(g.Limit-g.Queries, 0), g.Limit)
+	warnQuota(q)
+	return nil
+}
+
+// warnQuota prints warnings about quotas close to exhaustion to stderr.
+func warnQuota(q *goso.Quota) {
+	for _, w := range q.Warnings() {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s\n", app, w)
+	}
+}
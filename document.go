@@ -0,0 +1,327 @@
+package goso
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// nodeKind is the type of node in the document model shared by renderers.
+type nodeKind int
+
+const (
+	// block nodes
+	docRoot nodeKind = iota
+	paragraph
+	heading
+	codeBlock
+	blockquote
+	list
+	listItem
+	table
+	tableRow
+	tableCell
+	rule
+	// inline nodes
+	text
+	emphasis
+	strong
+	strike
+	inlineCode
+	link
+	image
+	lineBreak
+	keyboard
+	superscript
+	subscript
+)
+
+func (k nodeKind) isBlock() bool {
+	return k < text
+}
+
+// node is an element of the document model built from Stack Exchange HTML.
+type node struct {
+	kind     nodeKind
+	text     string // text, inlineCode and codeBlock content
+	attr     string // href of link, src of image, language hint of codeBlock ("none" disables highlighting)
+	level    int    // level of heading, first number of ordered list
+	ordered  bool   // list is ordered
+	header   bool   // tableCell is a header cell
+	align    string // tableCell alignment: left, center or right
+	children []*node
+}
+
+var (
+	spacePattern    = regexp.MustCompile(`[ \t\r\n\f]+`)
+	langHintPattern = regexp.MustCompile(`^\s*language(-all)?:\s*(\S+)\s*$`)
+)
+
+// parseDocument converts HTML body of a question or an answer into the document model.
+func parseDocument(body string) *node {
+	ctx := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(body), ctx)
+	root := &node{kind: docRoot}
+	if err != nil {
+		// html parser does not fail on malformed markup, only on reader errors
+		root.children = []*node{{kind: paragraph, children: []*node{{kind: text, text: body}}}}
+		return root
+	}
+	p := &docParser{}
+	for _, n := range nodes {
+		root.children = append(root.children, p.convert(n)...)
+	}
+	root.children = groupInlines(root.children)
+	return root
+}
+
+type docParser struct {
+	langHint    string // from <!-- language: lang-x --> comment, applies to the next code block
+	langHintAll string // from <!-- language-all: lang-x --> comment, applies to all following code blocks
+}
+
+func (p *docParser) convertChildren(n *html.Node) []*node {
+	var nodes []*node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, p.convert(c)...)
+	}
+	return nodes
+}
+
+func (p *docParser) convert(n *html.Node) []*node {
+	switch n.Type {
+	case html.TextNode:
+		return []*node{{kind: text, text: spacePattern.ReplaceAllString(stripControl(n.Data), " ")}}
+	case html.CommentNode:
+		if m := langHintPattern.FindStringSubmatch(n.Data); m != nil {
+			if m[1] != "" {
+				p.langHintAll = normalizeLang(m[2])
+			} else {
+				p.langHint = normalizeLang(m[2])
+			}
+		}
+		return nil
+	case html.ElementNode:
+	default:
+		return p.convertChildren(n)
+	}
+	switch n.DataAtom {
+	case atom.P:
+		return []*node{{kind: paragraph, children: p.convertChildren(n)}}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return []*node{{kind: heading, level: int(n.Data[1] - '0'), children: p.convertChildren(n)}}
+	case atom.Pre:
+		return []*node{p.convertPre(n)}
+	case atom.Blockquote:
+		return []*node{{kind: blockquote, children: groupInlines(p.convertChildren(n))}}
+	case atom.Ul, atom.Ol, atom.Dl:
+		l := &node{kind: list, ordered: n.DataAtom == atom.Ol, level: 1}
+		if start, err := strconv.Atoi(getAttr(n, "start")); err == nil && l.ordered {
+			l.level = start
+		}
+		for _, c := range p.convertChildren(n) {
+			if c.kind != listItem {
+				if c.kind == text && strings.TrimSpace(c.text) == "" {
+					continue
+				}
+				c = &node{kind: listItem, children: []*node{c}}
+			}
+			l.children = append(l.children, c)
+		}
+		return []*node{l}
+	case atom.Li, atom.Dd:
+		return []*node{{kind: listItem, children: groupInlines(p.convertChildren(n))}}
+	case atom.Dt:
+		return []*node{{kind: listItem, children: groupInlines([]*node{{kind: strong, children: p.convertChildren(n)}})}}
+	case atom.Table:
+		t := &node{kind: table}
+		collectRows(t, p.convertChildren(n))
+		return []*node{t}
+	case atom.Thead, atom.Tbody, atom.Tfoot:
+		return p.convertChildren(n)
+	case atom.Tr:
+		row := &node{kind: tableRow}
+		for _, c := range p.convertChildren(n) {
+			if c.kind == tableCell {
+				row.children = append(row.children, c)
+			}
+		}
+		return []*node{row}
+	case atom.Th, atom.Td:
+		cell := &node{kind: tableCell, header: n.DataAtom == atom.Th, align: cellAlign(n)}
+		cell.children = flattenInlines(p.convertChildren(n))
+		return []*node{cell}
+	case atom.Hr:
+		return []*node{{kind: rule}}
+	case atom.Em, atom.I:
+		return []*node{{kind: emphasis, children: p.convertChildren(n)}}
+	case atom.Strong, atom.B:
+		return []*node{{kind: strong, children: p.convertChildren(n)}}
+	case atom.Del, atom.S, atom.Strike:
+		return []*node{{kind: strike, children: p.convertChildren(n)}}
+	case atom.Code:
+		return []*node{{kind: inlineCode, text: textContent(n)}}
+	case atom.Kbd:
+		return []*node{{kind: keyboard, children: p.convertChildren(n)}}
+	case atom.Sup:
+		return []*node{{kind: superscript, children: p.convertChildren(n)}}
+	case atom.Sub:
+		return []*node{{kind: subscript, children: p.convertChildren(n)}}
+	case atom.A:
+		return []*node{{kind: link, attr: getAttr(n, "href"), children: p.convertChildren(n)}}
+	case atom.Img:
+		return []*node{{kind: image, attr: getAttr(n, "src"), text: getAttr(n, "alt")}}
+	case atom.Br:
+		return []*node{{kind: lineBreak}}
+	case atom.Script, atom.Style, atom.Head, atom.Title:
+		return nil
+	}
+	// div, span and any unknown element are transparent
+	return p.convertChildren(n)
+}
+
+func (p *docParser) convertPre(n *html.Node) *node {
+	lang := langFromClass(getAttr(n, "class"))
+	for c := n.FirstChild; c != nil && lang == ""; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Code {
+			lang = langFromClass(getAttr(c, "class"))
+		}
+	}
+	if lang == "" {
+		lang = p.langHint
+	}
+	if lang == "" {
+		lang = p.langHintAll
+	}
+	p.langHint = ""
+	code := strings.TrimSuffix(textContent(n), "\n")
+	return &node{kind: codeBlock, attr: lang, text: code}
+}
+
+// langFromClass extracts language hint from classes like "lang-py" or "language-python".
+func langFromClass(class string) string {
+	for _, c := range strings.Fields(class) {
+		for _, prefix := range []string{"lang-", "language-"} {
+			if lang, ok := strings.CutPrefix(c, prefix); ok && lang != "" {
+				return normalizeLang(lang)
+			}
+		}
+	}
+	return ""
+}
+
+func normalizeLang(lang string) string {
+	lang = strings.ToLower(strings.TrimPrefix(lang, "lang-"))
+	if lang == "default" {
+		return ""
+	}
+	return lang
+}
+
+func cellAlign(n *html.Node) string {
+	if align := strings.ToLower(getAttr(n, "align")); align != "" {
+		return align
+	}
+	for _, decl := range strings.Split(getAttr(n, "style"), ";") {
+		prop, value, ok := strings.Cut(decl, ":")
+		if ok && strings.TrimSpace(strings.ToLower(prop)) == "text-align" {
+			return strings.TrimSpace(strings.ToLower(value))
+		}
+	}
+	return ""
+}
+
+func collectRows(t *node, nodes []*node) {
+	for _, c := range nodes {
+		if c.kind == tableRow && len(c.children) > 0 {
+			t.children = append(t.children, c)
+		}
+	}
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return stripControl(a.Val)
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(stripControl(n.Data))
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+			sb.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// groupInlines wraps runs of inline nodes found among blocks into paragraphs.
+func groupInlines(nodes []*node) []*node {
+	var (
+		grouped []*node
+		para    *node
+	)
+	for _, n := range nodes {
+		if n.kind.isBlock() {
+			para = nil
+			grouped = append(grouped, n)
+			continue
+		}
+		if para == nil {
+			if n.kind == text && strings.TrimSpace(n.text) == "" {
+				continue
+			}
+			para = &node{kind: paragraph}
+			grouped = append(grouped, para)
+		}
+		para.children = append(para.children, n)
+	}
+	return grouped
+}
+
+// flattenInlines replaces blocks with their inline content separated by line breaks,
+// table cells can only hold inline nodes.
+func flattenInlines(nodes []*node) []*node {
+	var flat []*node
+	for _, n := range nodes {
+		if !n.kind.isBlock() {
+			flat = append(flat, n)
+			continue
+		}
+		if len(flat) > 0 {
+			flat = append(flat, &node{kind: lineBreak})
+		}
+		if n.kind == codeBlock {
+			flat = append(flat, &node{kind: inlineCode, text: n.text})
+			continue
+		}
+		flat = append(flat, flattenInlines(n.children)...)
+	}
+	return flat
+}
+
+// stripControl removes control characters other than newline and tab from
+// untrusted text, so it cannot send escape sequences to the terminal.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,246 @@
+package goso
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "nested tags",
+			body: "<p><strong>bold <em>and italic</em></strong> text</p>",
+			want: "bold and italic text",
+		},
+		{
+			name: "attributes on code",
+			body: `<pre class="lang-go s-code-block"><code class="hljs language-go">x := 1</code></pre>`,
+			want: "x := 1",
+		},
+		{
+			name: "entities inside code",
+			body: "<p><code>a &lt;b&gt; &amp;&amp; c</code></p><pre><code>if a &lt; b {\n}\n</code></pre>",
+			want: "a <b> && c\n\nif a < b {\n}",
+		},
+		{
+			name: "unknown tags",
+			body: `<p><span class="x">one</span> <foo bar="baz">two</foo></p><div><section>three</section></div>`,
+			want: "one two\n\nthree",
+		},
+		{
+			name: "links",
+			body: `<p><a href="https://go.dev" rel="nofollow">Go</a> and <a href="https://pkg.go.dev">https://pkg.go.dev</a></p>`,
+			want: "Go https://go.dev and https://pkg.go.dev",
+		},
+		{
+			name: "lists",
+			body: "<ul><li>one</li><li><p>two</p><ol><li>three</li></ol></li></ul>",
+			want: " - one\n - two\n\n    1. three",
+		},
+		{
+			name: "blockquote",
+			body: "<blockquote><p>quote</p></blockquote>",
+			want: "> quote",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plainText(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("\ngot:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+var (
+	tagPattern    = regexp.MustCompile(`</?[a-zA-Z][^<>]*>`)
+	entityPattern = regexp.MustCompile(`&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);?`)
+	// entities Markdown decodes, the ones escaped with backslash are literal text
+	mdEntityPattern = regexp.MustCompile(`(?:^|[^\\])(&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);)`)
+	// tags and autolinks Markdown output is allowed to contain
+	mdTagPattern = regexp.MustCompile(`</?(kbd|sup|sub|br)>|<[a-zA-Z][a-zA-Z0-9+.-]*:[^<>\s]*>`)
+)
+
+// wellFormed reports whether text and attribute values of HTML body contain
+// no markup characters, so any tag or entity in rendered output is a leftover
+// of the markup. Text outside of code may contain characters of decoded, which
+// the output is expected to escape.
+func wellFormed(body, decoded string) bool {
+	ctx := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(body), ctx)
+	if err != nil {
+		return false
+	}
+	var walk func(n *html.Node, inCode bool) bool
+	walk = func(n *html.Node, inCode bool) bool {
+		inCode = inCode || n.DataAtom == atom.Pre || n.DataAtom == atom.Code
+		markup := "<>&"
+		if !inCode {
+			markup = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(decoded, r) {
+					return -1
+				}
+				return r
+			}, markup)
+		}
+		if n.Type == html.TextNode && strings.ContainsAny(n.Data, markup) {
+			return false
+		}
+		for _, a := range n.Attr {
+			if strings.ContainsAny(a.Val, "<>&") {
+				return false
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !walk(c, inCode) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, n := range nodes {
+		if !walk(n, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLeftovers fails the test when output contains tags or entities matching entity.
+func checkLeftovers(t *testing.T, output, kind string, entity *regexp.Regexp) {
+	t.Helper()
+	if tag := tagPattern.FindString(output); tag != "" {
+		t.Fatalf("tag %q leaked into %s: %q", tag, kind, output)
+	}
+	if e := entity.FindString(output); e != "" {
+		t.Fatalf("entity %q leaked into %s: %q", e, kind, output)
+	}
+}
+
+func addBodies(f *testing.F) {
+	f.Add("<p>Hello <b>world</b></p>")
+	f.Add(`<pre class="lang-c"><code class="x">int a &lt; b;</code></pre>`)
+	f.Add("<ul><li><p>a<li>b</ul><table><tr><th>h<td>d</table>")
+	f.Add("<blockquote><blockquote><p>x</blockquote>")
+	f.Add("<strong><pre>code</pre></strong><unknown attr=1>u</unknown><a href=x>")
+	f.Add("<p>&amp;copy; ~~x~~</p>")
+	for _, name := range []string{"answers", "questions"} {
+		file, close, err := openFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		var resp StackOverflowResult
+		err = json.NewDecoder(file).Decode(&resp)
+		close()
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, item := range resp.Items {
+			f.Add(item.Body)
+		}
+	}
+}
+
+func FuzzRenderBody(f *testing.F) {
+	addBodies(f)
+	rd, err := newRenderer(&Config{Style: "onedark", Lexer: "plaintext"})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, body string) {
+		text, err := plainText(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sb strings.Builder
+		if err = rd.renderBody(body, &sb); err != nil {
+			t.Fatal(err)
+		}
+		if !wellFormed(body, "") {
+			return
+		}
+		checkLeftovers(t, text, "plain text", entityPattern)
+		checkLeftovers(t, ansiPattern.ReplaceAllString(sb.String(), ""), "terminal output", entityPattern)
+	})
+}
+
+func FuzzMarkdown(f *testing.F) {
+	addBodies(f)
+	f.Fuzz(func(t *testing.T, body string) {
+		md := Markdown(body)
+		// decoded ampersands must not form entities again
+		if !wellFormed(body, "&") {
+			return
+		}
+		checkLeftovers(t, mdTagPattern.ReplaceAllString(md, ""), "markdown", mdEntityPattern)
+	})
+}
+
+func TestLexerFor(t *testing.T) {
+	tests := []struct {
+		name  string
+		lexer string
+		tags  []string
+		lang  string
+		code  string
+		want  string
+	}{
+		{name: "override", lexer: "go", tags: []string{"python"}, lang: "js", want: "Go"},
+		{name: "hint", tags: []string{"python"}, lang: "js", want: "JavaScript"},
+		{name: "none hint", tags: []string{"python"}, lang: "none", want: "fallback"},
+		{name: "tags", tags: []string{"arrays", "python-3.x"}, want: "Python"},
+		{name: "tag alias", tags: []string{"pandas"}, want: "Python"},
+		{name: "react tag", tags: []string{"reactjs"}, want: "react"},
+		{name: "analyse", code: "#!/bin/bash\necho hi\n", want: "Bash"},
+		{name: "fallback", tags: []string{"arrays"}, code: "x", want: "fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd, err := newRenderer(&Config{Lexer: tt.lexer})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rd.tags = tt.tags
+			if got := rd.lexerFor(tt.code, tt.lang).Config().Name; got != tt.want {
+				t.Fatalf("expected %s lexer, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStripControl(t *testing.T) {
+	osc := "\x1b]52;c;ZXZpbA==\a"
+	results := []*Result{{
+		Title: "title" + osc,
+		Link:  "https://stackoverflow.com/q/1" + osc,
+		Answers: []*Answer{{
+			Title:  "title" + osc,
+			Author: "author\x9b2J",
+			Body:   "<p>text" + osc + "\tand\x00more<a href=\"https://x.com/" + osc + "\">link</a></p><pre><code>code" + osc + "\nline</code></pre>",
+		}},
+	}}
+	out, err := RenderResults(&Config{Style: "onedark", Lexer: "plaintext"}, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = ansiPattern.ReplaceAllString(out, "")
+	if i := strings.IndexFunc(out, func(r rune) bool { return r < ' ' && r != '\n' && r != '\t' || r >= 0x7f && r < 0xa0 }); i != -1 {
+		t.Fatalf("control character %q leaked into output: %q", out[i], out)
+	}
+	if !strings.Contains(out, "code]52;c;ZXZpbA==\nline") {
+		t.Fatalf("text around control characters should be kept: %q", out)
+	}
+}
@@ -0,0 +1,95 @@
+package goso
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+)
+
+// APIError is an error reported by Stack Exchange or Google API.
+// Use errors.As to inspect it.
+type APIError struct {
+	// API is the name of API that returned the error
+	API        string
+	StatusCode int
+	// ID is error_id of Stack Exchange API or error code of Google API
+	ID int
+	// Name is error_name of Stack Exchange API (e.g. throttle_violation) or reason of Google API (e.g. keyInvalid)
+	Name    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Name == "" && e.Message == "" {
+		return fmt.Sprintf("failed connecting to %s: %d %s", e.API, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Name == "" {
+		return fmt.Sprintf("%s error %d: %s", e.API, e.ID, e.Message)
+	}
+	return fmt.Sprintf("%s error %d %s: %s", e.API, e.ID, e.Name, e.Message)
+}
+
+// Throttled reports whether the request was rejected because of too many requests or exhausted quota.
+func (e *APIError) Throttled() bool {
+	switch e.Name {
+	case "throttle_violation", "dailyLimitExceeded", "rateLimitExceeded", "quotaExceeded", "userRateLimitExceeded":
+		return true
+	}
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// stackExchangeEnvelope holds error fields of Stack Exchange API response wrapper.
+type stackExchangeEnvelope struct {
+	ErrorID      int    `json:"error_id"`
+	ErrorName    string `json:"error_name"`
+	ErrorMessage string `json:"error_message"`
+}
+
+type googleEnvelope struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Errors  []struct {
+			Message string `json:"message"`
+			Domain  string `json:"domain"`
+			Reason  string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// readErrorBody reads a reasonable part of error response body.
+func readErrorBody(res *http.Response) []byte {
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	return data
+}
+
+// stackExchangeError returns *APIError decoded from failed Stack Exchange API response.
+func stackExchangeError(res *http.Response) error {
+	apiErr := &APIError{API: "Stack Exchange API", StatusCode: res.StatusCode}
+	var envelope stackExchangeEnvelope
+	if json.Unmarshal(readErrorBody(res), &envelope) == nil && envelope.ErrorName != "" {
+		apiErr.ID = envelope.ErrorID
+		apiErr.Name = envelope.ErrorName
+		apiErr.Message = html.UnescapeString(envelope.ErrorMessage)
+	}
+	return apiErr
+}
+
+// googleError returns *APIError decoded from failed Google API response.
+func googleError(res *http.Response) error {
+	apiErr := &APIError{API: "Google API", StatusCode: res.StatusCode}
+	var envelope googleEnvelope
+	if json.Unmarshal(readErrorBody(res), &envelope) == nil && envelope.Error.Code != 0 {
+		apiErr.ID = envelope.Error.Code
+		apiErr.Name = envelope.Error.Status
+		apiErr.Message = envelope.Error.Message
+		if len(envelope.Error.Errors) > 0 {
+			apiErr.Name = envelope.Error.Errors[0].Reason
+			apiErr.Message = envelope.Error.Errors[0].Message
+		}
+	}
+	return apiErr
+}
@@ -0,0 +1,105 @@
+package goso
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStackExchangeError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error_id": 400, "error_name": "bad_parameter", "error_message": "ids &quot;x&quot; are invalid"}`)
+	}))
+	t.Cleanup(ts.Close)
+	conf := &Config{StackExchangeAPI: ts.URL, Client: ts.Client()}
+	err := FetchStackOverflow(conf, map[int]*Result{1: {QuestionId: 1}})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	want := APIError{
+		API:        "Stack Exchange API",
+		StatusCode: http.StatusBadRequest,
+		ID:         400,
+		Name:       "bad_parameter",
+		Message:    `ids "x" are invalid`,
+	}
+	if *apiErr != want {
+		t.Fatalf("expected %+v, got %+v", want, *apiErr)
+	}
+	if apiErr.Throttled() {
+		t.Fatal("bad_parameter is not throttling")
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		decode    func(*http.Response) error
+		status    int
+		body      string
+		err       string
+		throttled bool
+	}{
+		{
+			name:      "stack exchange throttle",
+			decode:    stackExchangeError,
+			status:    http.StatusBadRequest,
+			body:      `{"error_id": 502, "error_name": "throttle_violation", "error_message": "too many requests from this IP, more requests available in 60 seconds"}`,
+			err:       "Stack Exchange API error 502 throttle_violation: too many requests from this IP, more requests available in 60 seconds",
+			throttled: true,
+		},
+		{
+			name:   "stack exchange without envelope",
+			decode: stackExchangeError,
+			status: http.StatusServiceUnavailable,
+			body:   "<html>Service Unavailable</html>",
+			err:    "failed connecting to Stack Exchange API: 503 Service Unavailable",
+		},
+		{
+			name:   "google invalid key",
+			decode: googleError,
+			status: http.StatusBadRequest,
+			body: `{"error": {"code": 400, "message": "API key not valid. Please pass a valid API key.", "status": "INVALID_ARGUMENT",
+				"errors": [{"message": "Bad Request", "domain": "usageLimits", "reason": "keyInvalid"}]}}`,
+			err: "Google API error 400 keyInvalid: Bad Request",
+		},
+		{
+			name:   "google without errors",
+			decode: googleError,
+			status: http.StatusForbidden,
+			body:   `{"error": {"code": 403, "message": "Custom Search API has not been used in project", "status": "PERMISSION_DENIED"}}`,
+			err:    "Google API error 403 PERMISSION_DENIED: Custom Search API has not been used in project",
+		},
+		{
+			name:   "google daily limit",
+			decode: googleError,
+			status: http.StatusTooManyRequests,
+			body: `{"error": {"code": 429, "message": "Quota exceeded", "status": "RESOURCE_EXHAUSTED",
+				"errors": [{"message": "Quota exceeded", "domain": "global", "reason": "rateLimitExceeded"}]}}`,
+			err:       "Google API error 429 rateLimitExceeded: Quota exceeded",
+			throttled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(tt.status)
+			rec.WriteString(tt.body)
+			err := fmt.Errorf("wrapped: %w", tt.decode(rec.Result()))
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %v", err)
+			}
+			if apiErr.Error() != tt.err {
+				t.Fatalf("expected %q, got %q", tt.err, apiErr.Error())
+			}
+			if apiErr.Throttled() != tt.throttled {
+				t.Fatalf("expected throttled %v", tt.throttled)
+			}
+		})
+	}
+}
@@ -4,10 +4,12 @@ go 1.23.0
 
 require (
 	github.com/alecthomas/chroma/v2 v2.14.0
-	golang.org/x/term v0.26.0
+	golang.org/x/net v0.33.0
+	golang.org/x/term v0.27.0
+	golang.org/x/text v0.21.0
 )
 
 require (
 	github.com/dlclark/regexp2 v1.11.0 // indirect
-	golang.org/x/sys v0.27.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 )
@@ -2,112 +2,45 @@ package goso
 
 import (
 	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
+	"log/slog"
 	"maps"
 	"net/http"
 	netUrl "net/url"
-	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/alecthomas/chroma/v2"
-	"github.com/alecthomas/chroma/v2/formatters"
-	"github.com/alecthomas/chroma/v2/lexers"
-	"github.com/alecthomas/chroma/v2/styles"
-	"golang.org/x/term"
 )
 
 const (
-	codeStartTag     string = "<pre><code>"
-	codeEndTag       string = "</code></pre>"
-	reset            string = "\033[0m"
-	bold             string = "\033[1m"
-	italic           string = "\033[3m"
-	strikethrough    string = "\033[9m"
-	gray             string = "\033[37m"
-	blue             string = "\033[36m"
-	green            string = "\033[32m"
-	yellow           string = "\033[33m"
-	magenta          string = "\033[35m"
-	questionColor    string = "\033[38;5;204m"
-	answerColor      string = "\033[38;5;255m"
-	downvoted        string = "\033[38;5;160m"
-	lightgray        string = "\033[38;5;248m"
-	urlColor         string = "\033[38;5;248m"
-	terminalMaxWidth int    = 80
-)
-
-var (
-	codeStartIdx  int
-	codeEndIdx    int
-	terminalWidth int
-	// https://meta.stackexchange.com/questions/1777/what-html-tags-are-allowed-on-stack-exchange-sites
-	codePattern  = regexp.MustCompile(`<pre\s.*?>`)
-	aHrefPattern = regexp.MustCompile(`(?s)<a\s+(?:[^>]*?\s+)?href=(["'])?([^\'" >]+)(.*?)?</a>`)
-	divPattern   = regexp.MustCompile(`<div.*?>`)
-	bqPattern    = regexp.MustCompile(`<blockquote.*?>`)
-	r            = strings.NewReplacer(
-		"<li><p>", "",
-		"<li><a href", "<a href",
-		"<p>", "",
-		"</p>", "",
-		"<strong>", bold,
-		"</strong>", reset,
-		"<em>", italic,
-		"</em>", reset,
-		"<i>", italic,
-		"</i>", reset,
-		"<ul>", "",
-		"</ul>", "",
-		"<ol>", "",
-		"</ol>", "",
-		"<li>", " - ",
-		"</li>", "",
-		"<b>", bold,
-		"</b>", reset,
-		"<h1>", bold,
-		"</h1>", reset,
-		"<h2>", bold,
-		"</h2>", reset,
-		"<h3>", bold,
-		"</h3>", reset,
-		"<h4>", bold,
-		"</h4>", reset,
-		"<h5>", bold,
-		"</h5>", reset,
-		"<h6>", bold,
-		"</h6>", reset,
-		"<br>", "\n",
-		"<blockquote>", italic,
-		"</blockquote>", reset,
-		"<del>", strikethrough,
-		"</del>", reset,
-		"<s>", strikethrough,
-		"</s>", reset,
-		"</div>", "",
-		"<code>", green,
-		"</code>", reset,
-		"<br />", "",
-		"<br/>", "",
-		"<hr />", "",
-		"<hr/>", "",
-		"<sup>", "",
-		"</sup>", "",
-		"<sub>", "",
-		"</sub>", "",
-		"<dl>", "",
-		"</dl>", "",
-		"<dt>", "",
-		"</dt>", "",
-		"<dd>", " - ",
-		"</dd>", "",
-		"<kbd>", bold,
-		"</kbd>", reset,
-	)
+	reset                string = "\033[0m"
+	bold                 string = "\033[1m"
+	italic               string = "\033[3m"
+	strikethrough        string = "\033[9m"
+	gray                 string = "\033[37m"
+	blue                 string = "\033[36m"
+	green                string = "\033[32m"
+	yellow               string = "\033[33m"
+	magenta              string = "\033[35m"
+	questionColor        string = "\033[38;5;204m"
+	answerColor          string = "\033[38;5;255m"
+	downvoted            string = "\033[38;5;160m"
+	lightgray            string = "\033[38;5;248m"
+	urlColor             string = "\033[38;5;248m"
+	terminalDefaultWidth int    = 80
+	terminalMinWidth     int    = 20
+	stackExchangeAPI     string = "https://api.stackexchange.com/2.3"
+	googleAPI            string = "https://www.googleapis.com/customsearch/v1"
+	googlePageSize       int    = 10
+	// Stack Exchange API limits
+	stackExchangeMaxIDs   int = 100
+	stackExchangePageSize int = 100
+	stackExchangeWorkers  int = 4
 )
 
 type GoogleSearchResult struct {
@@ -235,6 +168,7 @@ type StackOverflowResult struct {
 	HasMore        bool `json:"has_more"`
 	QuotaMax       int  `json:"quota_max"`
 	QuotaRemaining int  `json:"quota_remaining"`
+	Backoff        int  `json:"backoff"`
 }
 
 type StackOverflowQuestion struct {
@@ -266,6 +200,7 @@ type StackOverflowQuestion struct {
 	HasMore        bool `json:"has_more"`
 	QuotaMax       int  `json:"quota_max"`
 	QuotaRemaining int  `json:"quota_remaining"`
+	Backoff        int  `json:"backoff"`
 }
 
 type OpenSerpResult struct {
@@ -279,17 +214,64 @@ type OpenSerpResult struct {
 type Config struct {
 	ApiKey       string
 	SearchEngine string
-	Query        string
-	Style        string
+	// GooglePages limits the number of Google result pages of 10 items fetched to collect
+	// QuestionNum answered questions, each page uses one query of the daily quota.
+	// Zero means as many pages as QuestionNum requires, and one more when some of
+	// the links are skipped as not answered questions.
+	GooglePages int
+	// GoogleAPI overrides the URL of Google Custom Search API (default https://www.googleapis.com/customsearch/v1)
+	GoogleAPI string
+	Query     string
+	Style     string
+	// Lexer forces chroma lexer for all code blocks, empty value enables language detection
 	Lexer        string
 	QuestionNum  int
 	ShowQuestion bool
 	AnswerNum    int
+	// Width sets the number of columns to wrap text to, zero means terminal width
+	Width        int
 	OpenSerpHost string
 	OpenSerpPort int
-	Client       *http.Client
+	// StackExchangeAPI overrides the base URL of Stack Exchange API (default https://api.stackexchange.com/2.3)
+	StackExchangeAPI string
+	// StackExchangeKey is the key of registered Stack Exchange app, it raises daily quota of requests
+	StackExchangeKey string
+	// StackExchangeToken is OAuth access token of Stack Exchange user, it requires StackExchangeKey
+	StackExchangeToken string
+	// Sites lists Stack Exchange sites to search given by API parameters (e.g. superuser, unix)
+	// or domains, stackoverflow by default
+	Sites []string
+	// Retry sets retry policy of API requests, nil disables retries
+	Retry *RetryPolicy
+	// Quota tracks usage of API quotas when set
+	Quota *QuotaTracker
+	// Logger receives debug messages, e.g. about skipped search results
+	Logger *slog.Logger
+	// NumberCodeBlocks labels code blocks with their numbers in CodeBlocks
+	NumberCodeBlocks bool
+	Client           *http.Client
+}
+
+func (c *Config) stackExchangeAPI() string {
+	if c.StackExchangeAPI != "" {
+		return strings.TrimSuffix(c.StackExchangeAPI, "/")
+	}
+	return stackExchangeAPI
 }
+
+// stackExchangeURL returns URL of Stack Exchange API endpoint with app key and access token.
+func (c *Config) stackExchangeURL(endpoint string, params netUrl.Values) string {
+	if c.StackExchangeKey != "" {
+		params.Set("key", c.StackExchangeKey)
+	}
+	if c.StackExchangeToken != "" {
+		params.Set("access_token", c.StackExchangeToken)
+	}
+	return fmt.Sprintf("%s%s?%s", c.stackExchangeAPI(), endpoint, params.Encode())
+}
+
 type Answer struct {
+	AnswerId   int
 	Title      string
 	Author     string
 	Score      int
@@ -300,7 +282,11 @@ type Answer struct {
 }
 
 func (a *Answer) String() string {
-	line := strings.Repeat("─", terminalWidth)
+	return a.header(terminalDefaultWidth)
+}
+
+func (a *Answer) header(width int) string {
+	line := strings.Repeat("─", width)
 	color := yellow
 	if a.IsAccepted {
 		color = green
@@ -317,76 +303,148 @@ func (a *Answer) String() string {
 
 `,
 		line,
-		color, a.Score, reset, answerColor, a.Title, reset,
-		lightgray, a.Author, reset,
+		color, a.Score, reset, answerColor, stripControl(a.Title), reset,
+		lightgray, stripControl(a.Author), reset,
 		lightgray, a.Date.Format(time.RFC822), reset,
-		lightgray, a.Link, reset,
+		lightgray, stripControl(a.Link), reset,
 		line)
 }
 
 type Result struct {
-	Title       string
-	Link        string
-	QuestionId  int
+	Title      string
+	Link       string
+	QuestionId int
+	// Site is API parameter of Stack Exchange site the question belongs to, empty means stackoverflow
+	Site        string
+	Author      string
 	UpvoteCount int
 	Date        time.Time
+	Tags        []string
+	AnswerCount int
 	Body        string
 	Answers     []*Answer
+
+	answerId int // ID of answer the search result links to until question is resolved
 }
 
 func (r *Result) String() string {
-	line := strings.Repeat("─", terminalWidth)
+	return r.header(terminalDefaultWidth)
+}
+
+func (r *Result) header(width int) string {
+	line := strings.Repeat("─", width)
 	color := yellow
 	if r.UpvoteCount < 0 {
 		color = downvoted
 	}
 
+	var site string
+	if r.site() != defaultSite {
+		site = fmt.Sprintf("%sSite: %s%s\n", lightgray, stripControl(siteDomain(r.site())), reset)
+	}
 	return fmt.Sprintf(`
 %s
 %s[%d]%s %s%s[Question] %s%s
-%sDate: %s%s
+%s%sDate: %s%s
 %sLink: %s%s
 %s`,
 		line,
-		color, r.UpvoteCount, reset, bold, questionColor, r.Title, reset,
-		lightgray, r.Date.Format(time.RFC822), reset,
-		lightgray, r.Link, reset,
+		color, r.UpvoteCount, reset, bold, questionColor, stripControl(r.Title), reset,
+		site, lightgray, r.Date.Format(time.RFC822), reset,
+		lightgray, stripControl(r.Link), reset,
 		line)
 }
 
-func prepareText(text string) string {
-	return codePattern.ReplaceAllString(text, "<pre>")
+func FetchGoogle(conf *Config) ([]*Result, error) {
+	return FetchGoogleContext(context.Background(), conf)
 }
 
-func fmtText(text string) string {
-	t := r.Replace(html.UnescapeString(text))
-	t = strings.ReplaceAll(t, "<hr>", strings.Repeat("─", terminalWidth))
-	t = divPattern.ReplaceAllString(t, "")
-	t = aHrefPattern.ReplaceAllString(t, fmt.Sprintf("\n %s- $2%s", urlColor, reset))
-	t = bqPattern.ReplaceAllString(t, italic)
-	return t
+func FetchGoogleContext(ctx context.Context, conf *Config) ([]*Result, error) {
+	query := conf.Query
+	if sites := conf.sites(); !slices.Equal(sites, []string{defaultSite}) {
+		// search engine may be set up for Stack Overflow only
+		query += " " + siteQuery(sites)
+	}
+	pages := conf.GooglePages
+	adaptive := pages <= 0
+	if adaptive {
+		pages = (conf.QuestionNum + googlePageSize - 1) / googlePageSize
+	}
+	var results []*Result
+	var items int
+	start := 1
+	for page := 0; start > 0; page++ {
+		// default budget allows one more page only when links that are not
+		// answered questions left fewer questions than needed
+		if page >= pages && (!adaptive || page > pages || countQuestions(results) == items) {
+			break
+		}
+		gsResp, err := fetchGooglePage(ctx, conf, query, start)
+		if err != nil {
+			return nil, err
+		}
+		items += len(gsResp.Items)
+		pageResults, err := resolveAnswerLinks(ctx, conf, parseGoogle(conf, gsResp))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, pageResults...)
+		if countQuestions(results) >= conf.QuestionNum {
+			break
+		}
+		start = 0
+		if len(gsResp.Queries.NextPage) > 0 {
+			start = gsResp.Queries.NextPage[0].StartIndex
+		}
+	}
+	return results, nil
 }
 
-func FetchGoogle(conf *Config, results map[int]*Result) error {
-	url := fmt.Sprintf("https://www.googleapis.com/customsearch/v1?key=%s&cx=%s&q=%s",
-		conf.ApiKey, conf.SearchEngine, netUrl.QueryEscape(conf.Query))
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+func fetchGooglePage(ctx context.Context, conf *Config, query string, start int) (*GoogleSearchResult, error) {
+	url := fmt.Sprintf("%s?key=%s&cx=%s&q=%s&start=%d",
+		cmp.Or(conf.GoogleAPI, googleAPI), conf.ApiKey, conf.SearchEngine, netUrl.QueryEscape(query), start)
+	res, err := conf.get(ctx, url)
 	if err != nil {
-		return err
-	}
-	res, err := conf.Client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed connecting to Google API: check your internet connection")
+		return nil, connectionError(ctx, "Google API", err)
 	}
 	defer res.Body.Close()
 	if res.StatusCode > 299 {
-		return fmt.Errorf("failed connecting to Google API: %s", res.Status)
+		return nil, googleError(res)
 	}
+	conf.Quota.recordGoogle(res)
 	var gsResp GoogleSearchResult
 	err = json.NewDecoder(res.Body).Decode(&gsResp)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return &gsResp, nil
+}
+
+// countQuestions returns the number of distinct questions among results.
+func countQuestions(results []*Result) int {
+	type key struct {
+		site string
+		id   int
+	}
+	seen := make(map[key]bool, len(results))
+	for _, r := range results {
+		seen[key{r.site(), r.QuestionId}] = true
+	}
+	return len(seen)
+}
+
+func connectionError(ctx context.Context, api string, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, ErrCacheMiss) {
+		return fmt.Errorf("failed connecting to %s: %w", api, ErrCacheMiss)
 	}
+	return fmt.Errorf("failed connecting to %s: check your internet connection", api)
+}
+
+func parseGoogle(conf *Config, gsResp *GoogleSearchResult) []*Result {
+	results := make([]*Result, 0, len(gsResp.Items))
 	for _, item := range gsResp.Items {
 		var upvoteCount int
 		var dateCreated time.Time
@@ -399,224 +457,352 @@ func FetchGoogle(conf *Config, results map[int]*Result) error {
 			upvoteCount, _ = strconv.Atoi(question.Upvotecount)
 			dateCreated, _ = time.Parse("2006-01-02T15:04:05", question.Datecreated)
 		}
-		u, _ := netUrl.Parse(item.Link)
-		questionId, _ := strconv.Atoi(strings.Split(u.Path, "/")[2])
-		results[questionId] = &Result{
-			Title:       item.Title,
-			Link:        item.Link,
-			QuestionId:  questionId,
-			UpvoteCount: upvoteCount,
-			Date:        dateCreated,
+		res := newLinkResult(conf, item.Title, item.Link)
+		if res == nil {
+			continue
 		}
+		res.UpvoteCount = upvoteCount
+		res.Date = dateCreated
+		results = append(results, res)
 	}
-	return nil
+	return results
 }
 
-func FetchOpenSerp(conf *Config, results map[int]*Result) error {
-	url := fmt.Sprintf("http://%s:%d/google/search?lang=EN&limit=%d&text=%s&site=stackoverflow.com",
-		conf.OpenSerpHost, conf.OpenSerpPort, conf.QuestionNum, netUrl.QueryEscape(conf.Query))
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return err
+func FetchOpenSerp(conf *Config) ([]*Result, error) {
+	return FetchOpenSerpContext(context.Background(), conf)
+}
+
+func FetchOpenSerpContext(ctx context.Context, conf *Config) ([]*Result, error) {
+	var osResp []OpenSerpResult
+	for _, site := range conf.sites() {
+		items, err := fetchOpenSerpSite(ctx, conf, siteDomain(site))
+		if err != nil {
+			return nil, err
+		}
+		osResp = append(osResp, items...)
+	}
+	// results of several sites are interleaved by rank
+	slices.SortStableFunc(osResp, func(a, b OpenSerpResult) int {
+		return cmp.Compare(a.Rank, b.Rank)
+	})
+	results := make([]*Result, 0, len(osResp))
+	for _, item := range osResp {
+		if res := newLinkResult(conf, item.Title, item.URL); res != nil {
+			results = append(results, res)
+		}
 	}
-	res, err := conf.Client.Do(req)
+	return resolveAnswerLinks(ctx, conf, results)
+}
+
+func fetchOpenSerpSite(ctx context.Context, conf *Config, domain string) ([]OpenSerpResult, error) {
+	url := fmt.Sprintf("http://%s:%d/google/search?lang=EN&limit=%d&text=%s&site=%s",
+		conf.OpenSerpHost, conf.OpenSerpPort, conf.QuestionNum, netUrl.QueryEscape(conf.Query), netUrl.QueryEscape(domain))
+	res, err := conf.get(ctx, url)
 	if err != nil {
-		return fmt.Errorf("failed connecting to OpenSerp API: check your internet connection")
+		return nil, connectionError(ctx, "OpenSerp API", err)
 	}
 	defer res.Body.Close()
 	if res.StatusCode > 299 {
-		return fmt.Errorf("failed connecting to OpenSerp API: %s", res.Status)
+		return nil, fmt.Errorf("failed connecting to OpenSerp API: %s", res.Status)
 	}
 	var osResp []OpenSerpResult
 	err = json.NewDecoder(res.Body).Decode(&osResp)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for _, item := range osResp {
-		u, _ := netUrl.Parse(item.URL)
-		questionId, _ := strconv.Atoi(strings.Split(u.Path, "/")[2])
-		results[questionId] = &Result{
-			Title:      item.Title,
-			Link:       item.URL,
-			QuestionId: questionId,
+	return osResp, nil
+}
+
+func FetchStackExchange(conf *Config) ([]*Result, error) {
+	return FetchStackExchangeContext(context.Background(), conf)
+}
+
+func FetchStackExchangeContext(ctx context.Context, conf *Config) ([]*Result, error) {
+	var results []*Result
+	for _, site := range conf.sites() {
+		siteResults, err := fetchStackExchangeSite(ctx, conf, site)
+		if err != nil {
+			return nil, err
 		}
+		results = append(results, siteResults...)
 	}
-	return nil
+	return results, nil
 }
 
-func FetchStackOverflow(conf *Config, results map[int]*Result) error {
-	questions := make([]string, len(results))
-	var idx int
-	for question := range maps.Keys(results) {
-		questions[idx] = strconv.Itoa(question)
-		idx++
-	}
-	url := fmt.Sprintf("https://api.stackexchange.com/2.3/questions/%s/answers?order=desc&sort=votes&site=stackoverflow&filter=withbody",
-		netUrl.QueryEscape(strings.Join(questions, ";")))
-	//https://api.stackexchange.com/2.2/questions/6827752;48553152/?order=desc&sort=activity&site=stackoverflow&filter=withbody
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+func fetchStackExchangeSite(ctx context.Context, conf *Config, site string) ([]*Result, error) {
+	params := netUrl.Values{}
+	params.Set("order", "desc")
+	params.Set("sort", "relevance")
+	params.Set("q", conf.Query)
+	params.Set("answers", "1")
+	params.Set("pagesize", strconv.Itoa(conf.QuestionNum))
+	params.Set("site", site)
+	results, err := fetchStackExchangeSearch(ctx, conf, "/search/advanced", params)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(results) >= conf.QuestionNum {
+		return results, nil
 	}
-	res, err := conf.Client.Do(req)
+	params.Del("q")
+	params.Del("answers")
+	params.Set("title", conf.Query)
+	similar, err := fetchStackExchangeSearch(ctx, conf, "/similar", params)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, r := range similar {
+		if len(results) >= conf.QuestionNum {
+			break
+		}
+		if r.AnswerCount == 0 || slices.ContainsFunc(results, func(e *Result) bool { return e.QuestionId == r.QuestionId }) {
+			continue
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func fetchStackExchangeSearch(ctx context.Context, conf *Config, endpoint string, params netUrl.Values) ([]*Result, error) {
+	var seResp StackOverflowQuestion
+	if err := getStackExchange(ctx, conf, endpoint, params, &seResp); err != nil {
+		return nil, err
+	}
+	results := make([]*Result, 0, len(seResp.Items))
+	for _, item := range seResp.Items {
+		results = append(results, &Result{
+			Title:       html.UnescapeString(item.Title),
+			Link:        item.Link,
+			QuestionId:  item.QuestionID,
+			Author:      html.UnescapeString(item.Owner.DisplayName),
+			UpvoteCount: item.Score,
+			Date:        time.Unix(int64(item.CreationDate), 0).UTC(),
+			Tags:        item.Tags,
+			AnswerCount: item.AnswerCount,
+			Site:        params.Get("site"),
+		})
+	}
+	return results, nil
+}
+
+// stackExchangeWrapper is implemented by responses of Stack Exchange API,
+// it returns common fields of response wrapper.
+type stackExchangeWrapper interface {
+	wrapper() (hasMore bool, backoff, quotaRemaining, quotaMax int)
+}
+
+func (r *StackOverflowResult) wrapper() (bool, int, int, int) {
+	return r.HasMore, r.Backoff, r.QuotaRemaining, r.QuotaMax
+}
+
+func (r *StackOverflowQuestion) wrapper() (bool, int, int, int) {
+	return r.HasMore, r.Backoff, r.QuotaRemaining, r.QuotaMax
+}
+
+// getStackExchange requests Stack Exchange API endpoint and decodes response into v.
+func getStackExchange(ctx context.Context, conf *Config, endpoint string, params netUrl.Values, v stackExchangeWrapper) error {
+	res, err := conf.get(ctx, conf.stackExchangeURL(endpoint, params))
+	if err != nil {
+		return connectionError(ctx, "Stack Exchange API", err)
 	}
 	defer res.Body.Close()
 	if res.StatusCode > 299 {
-		return fmt.Errorf("failed connecting to Stack Overflow API: %s", res.Status)
+		return stackExchangeError(res)
 	}
-	var soResp StackOverflowResult
-	err = json.NewDecoder(res.Body).Decode(&soResp)
-	if err != nil {
+	if err = json.NewDecoder(res.Body).Decode(v); err != nil {
 		return err
 	}
-	soQuestions := make(map[int]string)
-	if conf.ShowQuestion {
-		url = fmt.Sprintf("https://api.stackexchange.com/2.3/questions/%s/?order=desc&sort=activity&site=stackoverflow&filter=withbody",
-			netUrl.QueryEscape(strings.Join(questions, ";")))
-		req, err := http.NewRequest(http.MethodGet, url, nil)
-		if err != nil {
-			return err
-		}
-		res, err := conf.Client.Do(req)
-		if err != nil {
+	_, backoff, quotaRemaining, quotaMax := v.wrapper()
+	conf.Retry.setBackoff(res, backoff)
+	conf.Quota.recordStackExchange(res, quotaRemaining, quotaMax)
+	return nil
+}
+
+// getStackExchangePages requests all pages of endpoint decoding each into value returned by newPage.
+func getStackExchangePages(ctx context.Context, conf *Config, endpoint string, params netUrl.Values, newPage func() stackExchangeWrapper) error {
+	params.Set("pagesize", strconv.Itoa(stackExchangePageSize))
+	for page := 1; ; page++ {
+		params.Set("page", strconv.Itoa(page))
+		v := newPage()
+		if err := getStackExchange(ctx, conf, endpoint, params, v); err != nil {
 			return err
 		}
-		defer res.Body.Close()
-		if res.StatusCode > 299 {
-			return fmt.Errorf("failed connecting to Stack Overflow API: %s", res.Status)
+		if hasMore, _, _, _ := v.wrapper(); !hasMore {
+			return nil
 		}
-		var soQuestionsResp StackOverflowQuestion
-		err = json.NewDecoder(res.Body).Decode(&soQuestionsResp)
-		if err != nil {
-			return err
+	}
+}
+
+func FetchStackOverflow(conf *Config, results map[int]*Result) error {
+	return FetchStackOverflowContext(context.Background(), conf, results)
+}
+
+func FetchStackOverflowContext(ctx context.Context, conf *Config, results map[int]*Result) error {
+	questions := make([]string, 0, len(results))
+	for _, id := range slices.Sorted(maps.Keys(results)) {
+		questions = append(questions, strconv.Itoa(id))
+	}
+	// results of one call belong to one site, see GetResults
+	site := defaultSite
+	for _, r := range results {
+		site = r.site()
+		break
+	}
+	// the API accepts up to 100 IDs in one request
+	chunks := slices.Collect(slices.Chunk(questions, stackExchangeMaxIDs))
+	answerPages := make([][]*StackOverflowResult, len(chunks))
+	questionPages := make([][]*StackOverflowQuestion, len(chunks))
+	// independent requests run concurrently, their pages are merged afterwards
+	var calls []func(ctx context.Context) error
+	for i, chunk := range chunks {
+		ids := netUrl.PathEscape(strings.Join(chunk, ";"))
+		params := netUrl.Values{}
+		params.Set("order", "desc")
+		params.Set("sort", "votes")
+		params.Set("site", site)
+		params.Set("filter", "withbody")
+		calls = append(calls, func(ctx context.Context) error {
+			return getStackExchangePages(ctx, conf, "/questions/"+ids+"/answers", params, func() stackExchangeWrapper {
+				page := &StackOverflowResult{}
+				answerPages[i] = append(answerPages[i], page)
+				return page
+			})
+		})
+		// questions provide tags for language detection even when their bodies are not shown,
+		// search engines other than Stack Exchange do not return them
+		questionParams := maps.Clone(params)
+		questionParams.Set("sort", "activity")
+		if !conf.ShowQuestion {
+			questionParams.Set("filter", "default")
 		}
-		for _, q := range soQuestionsResp.Items {
-			soQuestions[q.QuestionID] = q.Body
+		calls = append(calls, func(ctx context.Context) error {
+			return getStackExchangePages(ctx, conf, "/questions/"+ids, questionParams, func() stackExchangeWrapper {
+				page := &StackOverflowQuestion{}
+				questionPages[i] = append(questionPages[i], page)
+				return page
+			})
+		})
+	}
+	err := forEach(ctx, len(calls), stackExchangeWorkers, func(ctx context.Context, i int) error {
+		return calls[i](ctx)
+	})
+	if err != nil {
+		return err
+	}
+	for _, page := range slices.Concat(questionPages...) {
+		for _, q := range page.Items {
+			if result, ok := results[q.QuestionID]; ok {
+				if conf.ShowQuestion {
+					result.Body = q.Body
+				}
+				result.Author = html.UnescapeString(q.Owner.DisplayName)
+				result.Tags = q.Tags
+				result.AnswerCount = q.AnswerCount
+			}
 		}
 	}
-	for _, item := range soResp.Items {
-		result, ok := results[item.QuestionID]
-		if !ok {
-			continue
+	for _, page := range slices.Concat(answerPages...) {
+		for _, item := range page.Items {
+			result, ok := results[item.QuestionID]
+			if !ok {
+				continue
+			}
+			result.Answers = append(result.Answers,
+				&Answer{
+					AnswerId:   item.AnswerID,
+					Title:      result.Title,
+					Author:     html.UnescapeString(item.Owner.DisplayName),
+					Score:      item.Score,
+					Body:       item.Body,
+					Link:       fmt.Sprintf("https://%s/a/%d", siteDomain(site), item.AnswerID),
+					IsAccepted: item.IsAccepted,
+					Date:       time.Unix(int64(item.CreationDate), 0).UTC(),
+				})
 		}
-		result.Body = soQuestions[item.QuestionID]
-		result.Answers = append(result.Answers,
-			&Answer{
-				Title:      result.Title,
-				Author:     item.Owner.DisplayName,
-				Score:      item.Score,
-				Body:       item.Body,
-				Link:       fmt.Sprintf("https://stackoverflow.com/a/%d", item.AnswerID),
-				IsAccepted: item.IsAccepted,
-				Date:       time.Unix(int64(item.CreationDate), 0).UTC(),
-			})
 	}
 	return nil
 }
 
-func highlightText(text string, sb *strings.Builder, formatter chroma.Formatter, lexer chroma.Lexer, style *chroma.Style) error {
-	t := prepareText(text)
-	codeStartIdx = strings.Index(t, codeStartTag)
-	if codeStartIdx == -1 {
-		sb.WriteString(fmtText(t))
+// GetResults searches for questions and fetches their answers. It returns up to
+// conf.QuestionNum answered questions in display order, each with
+// at most conf.AnswerNum answers sorted by score.
+func GetResults(conf *Config,
+	searcher Searcher,
+	fetchAnswers func(*Config, map[int]*Result) error,
+) ([]*Result, error) {
+	return GetResultsContext(context.Background(), conf, searcher, withoutContext(fetchAnswers))
+}
+
+// GetResultsContext is like GetResults but aborts fetching when ctx is done.
+func GetResultsContext(ctx context.Context,
+	conf *Config,
+	searcher Searcher,
+	fetchAnswers func(context.Context, *Config, map[int]*Result) error,
+) ([]*Result, error) {
+	ranked, err := searcher.Search(ctx, conf)
+	if err != nil {
+		return nil, err
 	}
-	for codeStartIdx != -1 {
-		codeEndIdx = strings.Index(t, codeEndTag)
-		if codeEndIdx == -1 {
-			break
+	// question IDs are unique only within a site, answers are fetched for each site separately
+	bySite := make(map[string]map[int]*Result)
+	var sites []string
+	ranked = slices.DeleteFunc(ranked, func(r *Result) bool {
+		results, ok := bySite[r.site()]
+		if !ok {
+			results = make(map[int]*Result)
+			bySite[r.site()] = results
+			sites = append(sites, r.site())
 		}
-		sb.WriteString(fmtText(t[:codeStartIdx]))
-		iterator, err := lexer.Tokenise(nil, html.UnescapeString(t[codeStartIdx+len(codeStartTag):codeEndIdx]))
-		if err != nil {
-			return err
+		if _, ok := results[r.QuestionId]; ok {
+			return true
 		}
-		err = formatter.Format(sb, style, iterator)
+		results[r.QuestionId] = r
+		return false
+	})
+	for _, site := range sites {
+		err = fetchAnswers(ctx, conf, bySite[site])
 		if err != nil {
-			return err
-		}
-		t = t[codeEndIdx+len(codeEndTag):]
-		codeStartIdx = strings.Index(t, codeStartTag)
-		if codeStartIdx == -1 {
-			sb.WriteString(fmtText(t))
+			return nil, err
 		}
 	}
-	return nil
+	if searcher.Capabilities().Has(CapScores) {
+		slices.SortStableFunc(ranked, func(a, b *Result) int {
+			return cmp.Compare(b.UpvoteCount, a.UpvoteCount)
+		})
+	}
+	ranked = slices.DeleteFunc(ranked, func(r *Result) bool { return len(r.Answers) == 0 })
+	ranked = ranked[:min(len(ranked), conf.QuestionNum)]
+	for _, res := range ranked {
+		slices.SortStableFunc(res.Answers, func(a, b *Answer) int {
+			return cmp.Compare(b.Score, a.Score)
+		})
+		res.Answers = res.Answers[:min(len(res.Answers), conf.AnswerNum)]
+	}
+	return ranked, nil
 }
 
 func GetAnswers(conf *Config,
-	fetchResults func(*Config, map[int]*Result) error,
+	searcher Searcher,
 	fetchAnswers func(*Config, map[int]*Result) error,
 ) (string, error) {
-	var err error
-	if term.IsTerminal(0) {
-		terminalWidth, _, err = term.GetSize(0)
-		if err != nil {
-			return "", err
-		}
-		terminalWidth = min(terminalWidth, terminalMaxWidth)
-	} else {
-		terminalWidth = terminalMaxWidth
-	}
-	var answers strings.Builder
-	style := styles.Get(conf.Style)
-	if style == nil {
-		style = styles.Fallback
-	}
-	formatter := formatters.Get("terminal16m")
-	if formatter == nil {
-		formatter = formatters.Fallback
-	}
-	lexer := lexers.Get(conf.Lexer)
-	if lexer == nil {
-		lexer = lexers.Fallback
-	}
-	results := make(map[int]*Result)
-	err = fetchResults(conf, results)
-	if err != nil {
-		return "", err
-	}
-	err = fetchAnswers(conf, results)
+	return GetAnswersContext(context.Background(), conf, searcher, withoutContext(fetchAnswers))
+}
+
+// GetAnswersContext is like GetAnswers but aborts fetching and rendering when ctx is done.
+func GetAnswersContext(ctx context.Context,
+	conf *Config,
+	searcher Searcher,
+	fetchAnswers func(context.Context, *Config, map[int]*Result) error,
+) (string, error) {
+	results, err := GetResultsContext(ctx, conf, searcher, fetchAnswers)
 	if err != nil {
 		return "", err
 	}
-	var qIdx int
-	for _, res := range slices.Backward(slices.SortedStableFunc(maps.Values(results), func(a, b *Result) int {
-		return cmp.Compare(a.UpvoteCount, b.UpvoteCount)
-	})) {
-		if qIdx >= conf.QuestionNum {
-			break
-		}
-		if len(res.Answers) == 0 {
-			continue
-		}
-		qIdx++
-		slices.SortStableFunc(res.Answers, func(a, b *Answer) int {
-			return cmp.Compare(a.Score, b.Score)
-		})
-		answers.WriteString(res.String())
-		if conf.ShowQuestion {
-			var question strings.Builder
-			err = highlightText(res.Body, &question, formatter, lexer, style)
-			if err != nil {
-				return "", err
-			}
-			answers.WriteString("\n\n")
-			answers.WriteString(question.String())
-		}
-		var aIdx int
-		for _, ans := range slices.Backward(res.Answers) {
-			if aIdx >= conf.AnswerNum {
-				break
-			}
-			aIdx++
-			answers.WriteString(ans.String())
-			err = highlightText(ans.Body, &answers, formatter, lexer, style)
-			if err != nil {
-				return "", err
-			}
-		}
+	return RenderResultsContext(ctx, conf, results)
+}
+
+func withoutContext(fetchAnswers func(*Config, map[int]*Result) error) func(context.Context, *Config, map[int]*Result) error {
+	return func(_ context.Context, conf *Config, results map[int]*Result) error {
+		return fetchAnswers(conf, results)
 	}
-	return answers.String(), nil
 }
@@ -1,14 +1,20 @@
 package goso
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
-	netUrl "net/url"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -21,40 +27,26 @@ func openFile(path string) (*os.File, func(), error) {
 	return f, func() { f.Close() }, nil
 }
 
-func fetchGoogle(conf *Config, results map[int]*Result) error {
+type fileSearcher struct{}
+
+func (fileSearcher) Name() string { return "file" }
+
+func (fileSearcher) Capabilities() Capability { return CapScores }
+
+func (fileSearcher) Configured(conf *Config) error { return nil }
+
+func (fileSearcher) Search(ctx context.Context, conf *Config) ([]*Result, error) {
 	var gsResp GoogleSearchResult
 	f, close, err := openFile("goso")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer close()
 	err = json.NewDecoder(f).Decode(&gsResp)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for _, item := range gsResp.Items {
-		var upvoteCount int
-		var dateCreated time.Time
-		if len(item.Pagemap.Question) > 0 {
-			question := item.Pagemap.Question[0]
-			answerCount, _ := strconv.Atoi(question.Answercount)
-			if answerCount == 0 {
-				continue
-			}
-			upvoteCount, _ = strconv.Atoi(question.Upvotecount)
-			dateCreated, _ = time.Parse("2006-01-02T15:04:05", question.Datecreated)
-		}
-		u, _ := netUrl.Parse(item.Link)
-		questionId, _ := strconv.Atoi(strings.Split(u.Path, "/")[2])
-		results[questionId] = &Result{
-			Title:       item.Title,
-			Link:        item.Link,
-			QuestionId:  questionId,
-			UpvoteCount: upvoteCount,
-			Date:        dateCreated,
-		}
-	}
-	return nil
+	return parseGoogle(conf, &gsResp), nil
 }
 
 func fetchStackOverflow(conf *Config, results map[int]*Result) error {
@@ -76,7 +68,6 @@ func fetchStackOverflow(conf *Config, results map[int]*Result) error {
 	if err != nil {
 		return err
 	}
-	soQuestions := make(map[int]string)
 	if conf.ShowQuestion {
 		f, close, err := openFile("questions")
 		if err != nil {
@@ -89,7 +80,12 @@ func fetchStackOverflow(conf *Config, results map[int]*Result) error {
 			return err
 		}
 		for _, q := range soQuestionsResp.Items {
-			soQuestions[q.QuestionID] = q.Body
+			if result, ok := results[q.QuestionID]; ok {
+				result.Body = q.Body
+				result.Author = q.Owner.DisplayName
+				result.Tags = q.Tags
+				result.AnswerCount = q.AnswerCount
+			}
 		}
 	}
 	for _, item := range soResp.Items {
@@ -97,9 +93,9 @@ func fetchStackOverflow(conf *Config, results map[int]*Result) error {
 		if !ok {
 			continue
 		}
-		result.Body = soQuestions[item.QuestionID]
 		result.Answers = append(result.Answers,
 			&Answer{
+				AnswerId:   item.AnswerID,
 				Title:      result.Title,
 				Author:     item.Owner.DisplayName,
 				Score:      item.Score,
@@ -122,7 +118,7 @@ func BenchmarkGetAnswers(b *testing.B) {
 		AnswerNum:    10,
 	}
 	for i := 0; i < b.N; i++ {
-		_, err := GetAnswers(conf, fetchGoogle, fetchStackOverflow)
+		_, err := GetAnswers(conf, fileSearcher{}, fetchStackOverflow)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -137,9 +133,252 @@ func TestOutput(t *testing.T) {
 		ShowQuestion: true,
 		AnswerNum:    10,
 	}
-	answers, err := GetAnswers(conf, fetchGoogle, fetchStackOverflow)
+	answers, err := GetAnswers(conf, fileSearcher{}, fetchStackOverflow)
 	if err != nil {
 		t.Fatal(err)
 	}
 	fmt.Println(answers)
 }
+
+func TestGetResults(t *testing.T) {
+	conf := &Config{
+		QuestionNum: 3,
+		AnswerNum:   2,
+	}
+	results, err := GetResults(conf, fileSearcher{}, fetchStackOverflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != conf.QuestionNum {
+		t.Fatalf("expected %d results, got %d", conf.QuestionNum, len(results))
+	}
+	for i, res := range results {
+		if i > 0 && results[i-1].UpvoteCount < res.UpvoteCount {
+			t.Fatalf("results are not sorted by score: %d < %d", results[i-1].UpvoteCount, res.UpvoteCount)
+		}
+		if len(res.Answers) == 0 || len(res.Answers) > conf.AnswerNum {
+			t.Fatalf("unexpected number of answers for question %d: %d", res.QuestionId, len(res.Answers))
+		}
+		for j := 1; j < len(res.Answers); j++ {
+			if res.Answers[j-1].Score < res.Answers[j].Score {
+				t.Fatalf("answers of question %d are not sorted by score", res.QuestionId)
+			}
+		}
+	}
+}
+
+func TestGetAnswersConcurrent(t *testing.T) {
+	// plaintext lexer avoids chroma regexp timeouts under the race detector
+	conf := &Config{
+		Style:        "onedark",
+		Lexer:        "plaintext",
+		QuestionNum:  10,
+		ShowQuestion: true,
+		AnswerNum:    10,
+	}
+	expected, err := GetAnswers(conf, fileSearcher{}, fetchStackOverflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers, err := GetAnswers(conf, fileSearcher{}, fetchStackOverflow)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if answers != expected {
+				errs <- fmt.Errorf("concurrent output differs from sequential output")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestFetchStackOverflowPages(t *testing.T) {
+	var requests, running, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		ids, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/questions/"), "/answers")
+		if !ok {
+			// questions only provide tags
+			fmt.Fprint(w, `{"items": []}`)
+			return
+		}
+		if q := r.URL.Query(); q.Get("pagesize") != "100" {
+			t.Errorf("expected pagesize 100, got %q", q.Get("pagesize"))
+		}
+		questions := strings.Split(ids, ";")
+		if len(questions) > stackExchangeMaxIDs {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error_id": 400, "error_name": "bad_parameter", "error_message": "too many ids"}`)
+			return
+		}
+		// every question gets one answer on each of two pages
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		items := make([]string, len(questions))
+		for i, id := range questions {
+			items[i] = fmt.Sprintf(`{"question_id": %s, "answer_id": %s%d, "score": %d}`, id, id, page, 3-page)
+		}
+		fmt.Fprintf(w, `{"items": [%s], "has_more": %t}`, strings.Join(items, ","), page < 2)
+	}))
+	t.Cleanup(ts.Close)
+	results := make(map[int]*Result)
+	for id := 1; id <= 350; id++ {
+		results[id] = &Result{QuestionId: id}
+	}
+	conf := &Config{StackExchangeAPI: ts.URL, Client: ts.Client()}
+	if err := FetchStackOverflow(conf, results); err != nil {
+		t.Fatal(err)
+	}
+	// 4 chunks of IDs, 2 pages of answers and 1 page of questions each
+	if requests.Load() != 12 {
+		t.Fatalf("expected 12 requests, got %d", requests.Load())
+	}
+	if peak.Load() > int32(stackExchangeWorkers) {
+		t.Fatalf("expected at most %d concurrent requests, got %d", stackExchangeWorkers, peak.Load())
+	}
+	for id, res := range results {
+		if len(res.Answers) != 2 {
+			t.Fatalf("expected 2 answers of question %d, got %d", id, len(res.Answers))
+		}
+		if res.Answers[0].AnswerId != id*10+1 || res.Answers[1].AnswerId != id*10+2 {
+			t.Fatalf("unexpected answers of question %d: %d, %d", id, res.Answers[0].AnswerId, res.Answers[1].AnswerId)
+		}
+	}
+}
+
+// newDelayedServer returns Stack Exchange API server answering about question 1 after delay.
+func newDelayedServer(tb testing.TB, delay time.Duration, questionsStatus int) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		switch r.URL.Path {
+		case "/questions/1/answers":
+			fmt.Fprint(w, `{"items": [{"question_id": 1, "answer_id": 10, "score": 1, "body": "<p>answer</p>"}]}`)
+		case "/questions/1":
+			if questionsStatus != http.StatusOK {
+				w.WriteHeader(questionsStatus)
+				fmt.Fprint(w, `{"error_id": 403, "error_name": "access_denied", "error_message": "denied"}`)
+				return
+			}
+			fmt.Fprint(w, `{"items": [{"question_id": 1, "answer_count": 1, "body": "<p>question</p>", "owner": {"display_name": "asker"}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	tb.Cleanup(ts.Close)
+	return ts
+}
+
+func TestFetchStackOverflowConcurrent(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	ts := newDelayedServer(t, delay, http.StatusOK)
+	conf := &Config{ShowQuestion: true, StackExchangeAPI: ts.URL, Client: ts.Client()}
+	results := map[int]*Result{1: {QuestionId: 1}}
+	start := time.Now()
+	if err := FetchStackOverflow(conf, results); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*delay {
+		t.Fatalf("answers and question were fetched sequentially: %v", elapsed)
+	}
+	if res := results[1]; res.Body != "<p>question</p>" || len(res.Answers) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	ts = newDelayedServer(t, 0, http.StatusForbidden)
+	conf = &Config{ShowQuestion: true, StackExchangeAPI: ts.URL, Client: ts.Client()}
+	err := FetchStackOverflow(conf, map[int]*Result{1: {QuestionId: 1}})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Name != "access_denied" {
+		t.Fatalf("expected access_denied error, got %v", err)
+	}
+}
+
+func BenchmarkFetchStackOverflow(b *testing.B) {
+	const delay = 20 * time.Millisecond
+	ts := newDelayedServer(b, delay, http.StatusOK)
+	for _, showQuestion := range []bool{false, true} {
+		b.Run(fmt.Sprintf("question=%t", showQuestion), func(b *testing.B) {
+			conf := &Config{ShowQuestion: showQuestion, StackExchangeAPI: ts.URL, Client: ts.Client()}
+			start := time.Now()
+			for range b.N {
+				if err := FetchStackOverflow(conf, map[int]*Result{1: {QuestionId: 1}}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			// close to 1 when requests run concurrently, 2 when sequentially
+			b.ReportMetric(float64(time.Since(start))/float64(delay)/float64(b.N), "delays/op")
+		})
+	}
+}
+
+func TestFetchStackOverflowAuthors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/questions/1/answers":
+			fmt.Fprint(w, `{"items": [{"question_id": 1, "answer_id": 10, "owner": {"display_name": "O&#39;Brien"}}]}`)
+		case "/questions/1":
+			fmt.Fprint(w, `{"items": [{"question_id": 1, "owner": {"display_name": "Zo&#235; &amp; Co"}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	conf := &Config{ShowQuestion: true, StackExchangeAPI: ts.URL, Client: ts.Client()}
+	results := map[int]*Result{1: {QuestionId: 1}}
+	if err := FetchStackOverflow(conf, results); err != nil {
+		t.Fatal(err)
+	}
+	if author := results[1].Author; author != "Zoë & Co" {
+		t.Fatalf("expected unescaped question author, got %q", author)
+	}
+	if author := results[1].Answers[0].Author; author != "O'Brien" {
+		t.Fatalf("expected unescaped answer author, got %q", author)
+	}
+}
+
+func TestFetchStackOverflowTags(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/questions/1/answers":
+			fmt.Fprint(w, `{"items": [{"question_id": 1, "answer_id": 10, "body": "<pre><code>x</code></pre>"}]}`)
+		case "/questions/1":
+			if r.URL.Query().Get("filter") == "withbody" {
+				t.Error("question bodies requested without ShowQuestion")
+			}
+			fmt.Fprint(w, `{"items": [{"question_id": 1, "tags": ["go", "ssh"]}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	conf := &Config{StackExchangeAPI: ts.URL, Client: ts.Client()}
+	results := map[int]*Result{1: {QuestionId: 1}}
+	if err := FetchStackOverflowContext(context.Background(), conf, results); err != nil {
+		t.Fatal(err)
+	}
+	if tags := results[1].Tags; !slices.Equal(tags, []string{"go", "ssh"}) {
+		t.Fatalf("expected tags of the question, got %q", tags)
+	}
+	if body := results[1].Body; body != "" {
+		t.Fatalf("expected no question body, got %q", body)
+	}
+	if len(results[1].Answers) != 1 {
+		t.Fatalf("expected 1 answer, got %d", len(results[1].Answers))
+	}
+}
@@ -0,0 +1,137 @@
+package goso
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONSchemaVersion is incremented on every incompatible change of JSON output.
+const JSONSchemaVersion = 1
+
+// JSONOutput is the document produced by RenderJSON.
+type JSONOutput struct {
+	SchemaVersion int             `json:"schema_version"`
+	Query         string          `json:"query"`
+	Questions     []*JSONQuestion `json:"questions"`
+}
+
+// JSONQuestion is a single question of JSON output.
+// RenderNDJSON writes one JSONQuestion per line.
+type JSONQuestion struct {
+	QuestionId  int           `json:"question_id"`
+	Title       string        `json:"title"`
+	Link        string        `json:"link"`
+	Site        string        `json:"site"`
+	Score       int           `json:"score"`
+	Author      string        `json:"author,omitempty"`
+	Date        time.Time     `json:"creation_date"`
+	Tags        []string      `json:"tags,omitempty"`
+	AnswerCount int           `json:"answer_count,omitempty"`
+	Body        *JSONBody     `json:"body,omitempty"`
+	Answers     []*JSONAnswer `json:"answers"`
+}
+
+type JSONAnswer struct {
+	AnswerId   int       `json:"answer_id"`
+	Link       string    `json:"link"`
+	Score      int       `json:"score"`
+	Author     string    `json:"author"`
+	IsAccepted bool      `json:"is_accepted"`
+	Date       time.Time `json:"creation_date"`
+	Body       *JSONBody `json:"body"`
+}
+
+// JSONBody holds both the original HTML and its plain text conversion.
+type JSONBody struct {
+	HTML string `json:"html"`
+	Text string `json:"text"`
+}
+
+func newJSONBody(body string) (*JSONBody, error) {
+	if body == "" {
+		return nil, nil
+	}
+	text, err := plainText(body)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONBody{HTML: body, Text: text}, nil
+}
+
+// newJSONQuestion converts res into JSONQuestion, question body is included when showQuestion is set.
+func newJSONQuestion(res *Result, showQuestion bool) (*JSONQuestion, error) {
+	var body *JSONBody
+	if showQuestion {
+		var err error
+		body, err = newJSONBody(res.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	q := &JSONQuestion{
+		QuestionId:  res.QuestionId,
+		Title:       res.Title,
+		Link:        res.Link,
+		Site:        res.site(),
+		Score:       res.UpvoteCount,
+		Author:      res.Author,
+		Date:        res.Date,
+		Tags:        res.Tags,
+		AnswerCount: res.AnswerCount,
+		Body:        body,
+		Answers:     make([]*JSONAnswer, 0, len(res.Answers)),
+	}
+	for _, ans := range res.Answers {
+		body, err := newJSONBody(ans.Body)
+		if err != nil {
+			return nil, err
+		}
+		q.Answers = append(q.Answers, &JSONAnswer{
+			AnswerId:   ans.AnswerId,
+			Link:       ans.Link,
+			Score:      ans.Score,
+			Author:     ans.Author,
+			IsAccepted: ans.IsAccepted,
+			Date:       ans.Date,
+			Body:       body,
+		})
+	}
+	return q, nil
+}
+
+// RenderJSON writes results to w as a single JSONOutput document,
+// question bodies are included when conf.ShowQuestion is set.
+func RenderJSON(w io.Writer, conf *Config, results []*Result) error {
+	out := &JSONOutput{
+		SchemaVersion: JSONSchemaVersion,
+		Query:         conf.Query,
+		Questions:     make([]*JSONQuestion, 0, len(results)),
+	}
+	for _, res := range results {
+		q, err := newJSONQuestion(res, conf.ShowQuestion)
+		if err != nil {
+			return err
+		}
+		out.Questions = append(out.Questions, q)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// RenderNDJSON writes results to w as newline delimited JSON, one JSONQuestion per line,
+// question bodies are included when conf.ShowQuestion is set.
+func RenderNDJSON(w io.Writer, conf *Config, results []*Result) error {
+	enc := json.NewEncoder(w)
+	for _, res := range results {
+		q, err := newJSONQuestion(res, conf.ShowQuestion)
+		if err != nil {
+			return err
+		}
+		if err = enc.Encode(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
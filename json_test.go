@@ -0,0 +1,85 @@
+package goso
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRenderJSON(t *testing.T) {
+	conf := &Config{
+		Query:        "create array in c",
+		QuestionNum:  3,
+		ShowQuestion: true,
+		AnswerNum:    2,
+	}
+	results, err := GetResults(conf, fileSearcher{}, fetchStackOverflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = RenderJSON(&buf, conf, results); err != nil {
+		t.Fatal(err)
+	}
+	var out JSONOutput
+	if err = json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.SchemaVersion != JSONSchemaVersion || out.Query != conf.Query {
+		t.Fatalf("unexpected header: %d %q", out.SchemaVersion, out.Query)
+	}
+	if len(out.Questions) != len(results) {
+		t.Fatalf("expected %d questions, got %d", len(results), len(out.Questions))
+	}
+	for i, q := range out.Questions {
+		if q.QuestionId != results[i].QuestionId || len(q.Answers) != len(results[i].Answers) {
+			t.Fatalf("question %d does not match result", q.QuestionId)
+		}
+		for _, a := range q.Answers {
+			if a.AnswerId == 0 || a.Body == nil || a.Body.HTML == "" {
+				t.Fatalf("answer of question %d is incomplete: %+v", q.QuestionId, a)
+			}
+			if strings.Contains(a.Body.Text, "\033[") {
+				t.Fatalf("answer %d text contains escape sequences", a.AnswerId)
+			}
+		}
+	}
+}
+
+func TestRenderNDJSON(t *testing.T) {
+	conf := &Config{
+		QuestionNum: 3,
+		AnswerNum:   1,
+	}
+	results, err := GetResults(conf, fileSearcher{}, fetchStackOverflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// question bodies are left out without ShowQuestion
+	results[0].Body = "<p>question</p>"
+	var buf bytes.Buffer
+	if err = RenderNDJSON(&buf, conf, results); err != nil {
+		t.Fatal(err)
+	}
+	var lines int
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var q JSONQuestion
+		if err = json.Unmarshal(scanner.Bytes(), &q); err != nil {
+			t.Fatal(err)
+		}
+		if q.QuestionId != results[lines].QuestionId {
+			t.Fatalf("expected question %d, got %d", results[lines].QuestionId, q.QuestionId)
+		}
+		if q.Body != nil {
+			t.Fatalf("question %d has body without ShowQuestion", q.QuestionId)
+		}
+		lines++
+	}
+	if lines != len(results) {
+		t.Fatalf("expected %d lines, got %d", len(results), lines)
+	}
+}
@@ -0,0 +1,124 @@
+package goso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	netUrl "net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// questionPaths are the first path segments of question links, including localized sites.
+var questionPaths = []string{"questions", "q", "preguntas", "perguntas"}
+
+// questionLink is a link to Stack Exchange question or answer.
+type questionLink struct {
+	site   string
+	id     int
+	answer bool // id is answer ID
+}
+
+// parseQuestionLink classifies link found by search engine. It accepts
+// /questions/<id>, /q/<id> and /a/<id> links of any Stack Exchange site and
+// returns error describing why any other link is not a question.
+func parseQuestionLink(link string) (*questionLink, error) {
+	u, err := netUrl.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	site, ok := siteFromHost(u.Hostname())
+	if !ok {
+		return nil, fmt.Errorf("%s is not a Stack Exchange site", u.Hostname())
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 {
+		return nil, errors.New("not a question link")
+	}
+	id, err := strconv.Atoi(segments[1])
+	if err != nil || id <= 0 {
+		return nil, errors.New("not a question link")
+	}
+	switch {
+	case segments[0] == "a":
+		return &questionLink{site: site, id: id, answer: true}, nil
+	case slices.Contains(questionPaths, segments[0]):
+		return &questionLink{site: site, id: id}, nil
+	}
+	return nil, errors.New("not a question link")
+}
+
+// newLinkResult returns result for link to question or answer found by search engine,
+// it returns nil if link is not a question. Results of answer links should be
+// passed to resolveAnswerLinks.
+func newLinkResult(conf *Config, title, link string) *Result {
+	ql, err := parseQuestionLink(link)
+	if err != nil {
+		conf.logger().Debug("skipping search result", "link", link, "reason", err)
+		return nil
+	}
+	res := &Result{Title: title, Link: link, Site: ql.site, QuestionId: ql.id}
+	if ql.answer {
+		res.QuestionId = 0
+		res.answerId = ql.id
+	}
+	return res
+}
+
+// resolveAnswerLinks finds questions of results created from answer links with
+// Stack Exchange API. Results of answers that could not be found are removed.
+func resolveAnswerLinks(ctx context.Context, conf *Config, results []*Result) ([]*Result, error) {
+	answers := make(map[string][]string)
+	for _, res := range results {
+		if res.answerId != 0 && !slices.Contains(answers[res.site()], strconv.Itoa(res.answerId)) {
+			answers[res.site()] = append(answers[res.site()], strconv.Itoa(res.answerId))
+		}
+	}
+	questions := make(map[string]map[int]int)
+	for site, ids := range answers {
+		resolved, err := fetchAnswerQuestions(ctx, conf, site, ids)
+		if err != nil {
+			return nil, err
+		}
+		questions[site] = resolved
+	}
+	return slices.DeleteFunc(results, func(res *Result) bool {
+		if res.answerId == 0 {
+			return false
+		}
+		res.QuestionId = questions[res.site()][res.answerId]
+		if res.QuestionId == 0 {
+			conf.logger().Debug("skipping search result", "link", res.Link, "reason", "answer not found")
+			return true
+		}
+		return false
+	}), nil
+}
+
+// fetchAnswerQuestions returns question IDs of answers by their IDs.
+func fetchAnswerQuestions(ctx context.Context, conf *Config, site string, ids []string) (map[int]int, error) {
+	params := netUrl.Values{}
+	params.Set("site", site)
+	params.Set("pagesize", "100")
+	var soResp StackOverflowResult
+	if err := getStackExchange(ctx, conf, "/answers/"+netUrl.PathEscape(strings.Join(ids, ";")), params, &soResp); err != nil {
+		return nil, err
+	}
+	questions := make(map[int]int, len(soResp.Items))
+	for _, item := range soResp.Items {
+		questions[item.AnswerID] = item.QuestionID
+	}
+	return questions, nil
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func (c *Config) logger() *slog.Logger {
+	if c.Logger == nil {
+		return discardLogger
+	}
+	return c.Logger
+}
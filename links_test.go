@@ -0,0 +1,105 @@
+package goso
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseQuestionLink(t *testing.T) {
+	tests := []struct {
+		link   string
+		site   string
+		id     int
+		answer bool
+	}{
+		{link: "https://stackoverflow.com/questions/23330781/sort-go-map-values-by-keys", site: "stackoverflow", id: 23330781},
+		{link: "https://stackoverflow.com/questions/23330781", site: "stackoverflow", id: 23330781},
+		{link: "https://stackoverflow.com/questions/23330781/sort/23332089#23332089", site: "stackoverflow", id: 23330781},
+		{link: "https://stackoverflow.com/q/23330781/1234", site: "stackoverflow", id: 23330781},
+		{link: "https://stackoverflow.com/a/23332089", site: "stackoverflow", id: 23332089, answer: true},
+		{link: "https://www.superuser.com/questions/42/title", site: "superuser", id: 42},
+		{link: "https://ru.stackoverflow.com/questions/42/title", site: "ru.stackoverflow", id: 42},
+		{link: "https://es.stackoverflow.com/preguntas/42/title", site: "es.stackoverflow", id: 42},
+		{link: "https://unix.stackexchange.com/a/42/7", site: "unix", id: 42, answer: true},
+		{link: "https://stackoverflow.com/questions/tagged/go"},
+		{link: "https://stackoverflow.com/users/42/user"},
+		{link: "https://stackoverflow.com/questions"},
+		{link: "https://stackoverflow.com/"},
+		{link: "https://stackoverflow.com"},
+		{link: "https://stackoverflow.com/q/-1"},
+		{link: "https://github.com/questions/42"},
+		{link: "%%%"},
+	}
+	for _, tt := range tests {
+		ql, err := parseQuestionLink(tt.link)
+		if tt.id == 0 {
+			if err == nil {
+				t.Errorf("%s: expected error, got %+v", tt.link, ql)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.link, err)
+			continue
+		}
+		if *ql != (questionLink{site: tt.site, id: tt.id, answer: tt.answer}) {
+			t.Errorf("%s: unexpected %+v", tt.link, ql)
+		}
+	}
+}
+
+func TestFetchOpenSerpLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/google/search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"rank": 1, "url": "https://stackoverflow.com/a/200", "title": "Answer link"},
+			{"rank": 2, "url": "https://stackoverflow.com/questions/tagged/go", "title": "Tag page"},
+			{"rank": 3, "url": "https://stackoverflow.com/q/3", "title": "Short link"},
+			{"rank": 4, "url": "https://stackoverflow.com/a/404", "title": "Deleted answer"},
+			{"rank": 5, "url": "https://stackoverflow.com/questions/5/title", "title": "Question link"}
+		]`)
+	})
+	mux.HandleFunc("/answers/{ids}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("ids") != "200;404" || r.URL.Query().Get("site") != "stackoverflow" {
+			http.Error(w, "bad parameter", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"items": [{"answer_id": 200, "question_id": 1}]}`)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	var log bytes.Buffer
+	conf := &Config{
+		Query:            "sort maps",
+		QuestionNum:      5,
+		OpenSerpHost:     host,
+		StackExchangeAPI: ts.URL,
+		Client:           ts.Client(),
+		Logger:           slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+	conf.OpenSerpPort, _ = strconv.Atoi(port)
+	results, err := FetchOpenSerp(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, res := range results {
+		ids = append(ids, res.QuestionId)
+	}
+	if fmt.Sprint(ids) != "[1 3 5]" {
+		t.Fatalf("expected questions [1 3 5], got %v", ids)
+	}
+	for _, skipped := range []string{"questions/tagged/go", "a/404"} {
+		if !strings.Contains(log.String(), skipped) {
+			t.Fatalf("expected skipped %s to be logged, got %s", skipped, log.String())
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package goso
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	"~", `\~`,
+	"&", `\&`,
+)
+
+var (
+	// markers starting headings, blockquotes and bullet lists are escaped as a whole
+	mdLineStartPattern = regexp.MustCompile(`(?m)^([#>]|[-+](?: |$))`)
+	// backslash before a digit is literal, so only the delimiter of ordered lists is escaped
+	mdOrderedPattern  = regexp.MustCompile(`(?m)^(\d{1,9})([.)])( |$)`)
+	mdAutolinkPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^<>\s]*$`)
+)
+
+// Markdown converts HTML body of a question or an answer into GitHub Flavored Markdown.
+func Markdown(body string) string {
+	return mdBlocks(parseDocument(body).children)
+}
+
+func mdBlocks(nodes []*node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		b := mdBlock(n)
+		if b == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			// keep nested lists tight, CommonMark allows only lists
+			// starting with 1 to interrupt a paragraph
+			if n.kind == list && (!n.ordered || n.level == 1) {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(b)
+	}
+	return sb.String()
+}
+
+func mdBlock(n *node) string {
+	switch n.kind {
+	case paragraph:
+		return mdEscapeLineStart(strings.TrimSpace(mdInlines(n.children, mdText)))
+	case heading:
+		return strings.Repeat("#", n.level) + " " + strings.TrimSpace(mdInlines(n.children, mdHeading))
+	case codeBlock:
+		fence := "```"
+		for strings.Contains(n.text, fence) {
+			fence += "`"
+		}
+		lang := n.attr
+		if lang == "none" {
+			lang = ""
+		}
+		return fence + lang + "\n" + n.text + "\n" + fence
+	case blockquote:
+		return prefixLines(mdBlocks(n.children), "> ", ">")
+	case list:
+		items := make([]string, 0, len(n.children))
+		for i, item := range n.children {
+			marker := "- "
+			if n.ordered {
+				marker = strconv.Itoa(n.level+i) + ". "
+			}
+			content := mdBlocks(item.children)
+			indent := strings.Repeat(" ", len(marker))
+			items = append(items, marker+strings.TrimPrefix(prefixLines(content, indent, ""), indent))
+		}
+		return strings.Join(items, "\n")
+	case table:
+		return mdTable(n)
+	case rule:
+		return "---"
+	}
+	return strings.TrimSpace(mdInlines([]*node{n}, mdText))
+}
+
+// mdEscapeLineStart escapes text at the start of lines that would start another block.
+func mdEscapeLineStart(s string) string {
+	s = mdLineStartPattern.ReplaceAllString(s, `\$1`)
+	return mdOrderedPattern.ReplaceAllString(s, `${1}\${2}${3}`)
+}
+
+func mdTable(t *node) string {
+	var columns int
+	for _, row := range t.children {
+		columns = max(columns, len(row.children))
+	}
+	if columns == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for _, c := range cells {
+			sb.WriteString(" " + c + " |")
+		}
+		sb.WriteString("\n")
+	}
+	rows := t.children
+	header := make([]string, columns)
+	aligns := make([]string, columns)
+	if rows[0].children[0].header {
+		for i, cell := range rows[0].children {
+			header[i] = strings.TrimSpace(mdInlines(cell.children, mdTableCell))
+		}
+		rows = rows[1:]
+	}
+	for _, row := range t.children {
+		for i, cell := range row.children {
+			if aligns[i] == "" {
+				aligns[i] = cell.align
+			}
+		}
+	}
+	writeRow(header)
+	for i, align := range aligns {
+		switch align {
+		case "left":
+			aligns[i] = ":---"
+		case "center":
+			aligns[i] = ":---:"
+		case "right":
+			aligns[i] = "---:"
+		default:
+			aligns[i] = "---"
+		}
+	}
+	writeRow(aligns)
+	for _, row := range rows {
+		cells := make([]string, columns)
+		for i, cell := range row.children {
+			cells[i] = strings.TrimSpace(mdInlines(cell.children, mdTableCell))
+		}
+		writeRow(cells)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// mdContext is the place inline content is written to, it limits what the content may contain.
+type mdContext int
+
+const (
+	mdText      mdContext = iota
+	mdHeading             // single line
+	mdTableCell           // single line with escaped pipes
+)
+
+func mdInlines(nodes []*node, ctx mdContext) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		switch n.kind {
+		case text:
+			t := mdEscaper.Replace(n.text)
+			if ctx == mdTableCell {
+				t = strings.ReplaceAll(t, "|", `\|`)
+			}
+			sb.WriteString(t)
+		case emphasis:
+			sb.WriteString(mdWrap(mdInlines(n.children, ctx), "*"))
+		case strong:
+			sb.WriteString(mdWrap(mdInlines(n.children, ctx), "**"))
+		case strike:
+			sb.WriteString(mdWrap(mdInlines(n.children, ctx), "~~"))
+		case inlineCode, codeBlock:
+			sb.WriteString(mdCode(n.text, ctx == mdTableCell))
+		case keyboard:
+			sb.WriteString(mdWrapTag(mdInlines(n.children, ctx), "kbd"))
+		case superscript:
+			sb.WriteString(mdWrapTag(mdInlines(n.children, ctx), "sup"))
+		case subscript:
+			sb.WriteString(mdWrapTag(mdInlines(n.children, ctx), "sub"))
+		case link:
+			label := strings.TrimSpace(mdInlines(n.children, ctx))
+			switch {
+			case n.attr == "":
+				sb.WriteString(label)
+			case (label == "" || label == mdEscaper.Replace(n.attr)) && mdAutolinkPattern.MatchString(n.attr):
+				sb.WriteString("<" + n.attr + ">")
+			case label == "":
+				sb.WriteString("[" + mdEscaper.Replace(n.attr) + "](" + mdURL(n.attr) + ")")
+			default:
+				sb.WriteString("[" + label + "](" + mdURL(n.attr) + ")")
+			}
+		case image:
+			sb.WriteString("![" + mdEscaper.Replace(n.text) + "](" + mdURL(n.attr) + ")")
+		case lineBreak:
+			switch ctx {
+			case mdTableCell:
+				sb.WriteString("<br>")
+			case mdHeading:
+				// ATX heading ends with the line
+				sb.WriteString(" ")
+			default:
+				sb.WriteString("  \n")
+			}
+		case rule:
+		default:
+			// blocks nested in inline elements by malformed markup
+			sb.WriteString(mdInlines(n.children, ctx))
+		}
+	}
+	return sb.String()
+}
+
+// mdWrap surrounds s with marker keeping outer whitespace outside of it,
+// as CommonMark does not allow emphasis to start or end with a space.
+func mdWrap(s, marker string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	start := strings.Index(s, trimmed)
+	return s[:start] + marker + trimmed + marker + s[start+len(trimmed):]
+}
+
+func mdWrapTag(s, tag string) string {
+	return fmt.Sprintf("<%s>%s</%s>", tag, s, tag)
+}
+
+func mdCode(code string, inTable bool) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if inTable {
+		code = strings.ReplaceAll(code, "|", `\|`)
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+func mdURL(url string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(url)
+}
+
+func prefixLines(s, prefix, emptyPrefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RenderMarkdown writes results to w as a GitHub Flavored Markdown document,
+// question bodies are included when conf.ShowQuestion is set.
+func RenderMarkdown(w io.Writer, conf *Config, results []*Result) error {
+	var sb strings.Builder
+	for i, res := range results {
+		if i > 0 {
+			sb.WriteString("\n---\n\n")
+		}
+		fmt.Fprintf(&sb, "## [%s](%s)\n\n", mdEscaper.Replace(res.Title), mdURL(res.Link))
+		meta := []string{fmt.Sprintf("Score: %d", res.UpvoteCount)}
+		if res.site() != defaultSite {
+			meta = append(meta, "Site: "+siteDomain(res.site()))
+		}
+		if res.Author != "" {
+			meta = append(meta, "Author: "+mdEscaper.Replace(res.Author))
+		}
+		if !res.Date.IsZero() {
+			meta = append(meta, "Date: "+res.Date.Format(time.DateOnly))
+		}
+		if len(res.Tags) > 0 {
+			tags := make([]string, len(res.Tags))
+			for i, tag := range res.Tags {
+				tags[i] = mdCode(tag, false)
+			}
+			meta = append(meta, "Tags: "+strings.Join(tags, " "))
+		}
+		sb.WriteString(strings.Join(meta, " · ") + "\n\n")
+		if conf.ShowQuestion && res.Body != "" {
+			sb.WriteString(Markdown(res.Body) + "\n\n")
+		}
+		for _, ans := range res.Answers {
+			accepted := ""
+			if ans.IsAccepted {
+				accepted = " ✔ accepted"
+			}
+			fmt.Fprintf(&sb, "### [Answer](%s) by %s (score %d%s)\n\n", mdURL(ans.Link), mdEscaper.Replace(ans.Author), ans.Score, accepted)
+			if !ans.Date.IsZero() {
+				fmt.Fprintf(&sb, "Date: %s\n\n", ans.Date.Format(time.DateOnly))
+			}
+			sb.WriteString(Markdown(ans.Body) + "\n\n")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
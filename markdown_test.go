@@ -0,0 +1,131 @@
+package goso
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "paragraphs",
+			body: "<p>Use <code>sort.Slice</code> and <strong>bold </strong>text</p>\n\n<p>second <em>line</em></p>",
+			want: "Use `sort.Slice` and **bold** text\n\nsecond *line*",
+		},
+		{
+			name: "link",
+			body: `<p>See <a href="https://go.dev/doc">docs</a> or <a href="https://go.dev">https://go.dev</a></p>`,
+			want: "See [docs](https://go.dev/doc) or <https://go.dev>",
+		},
+		{
+			name: "code block with class",
+			body: `<pre class="lang-py s-code-block"><code class="hljs language-python">print(&quot;a&lt;b&quot;)` + "\n</code></pre>",
+			want: "```py\nprint(\"a<b\")\n```",
+		},
+		{
+			name: "code block with comment hint",
+			body: "<!-- language: lang-js -->\n<pre><code>var x = 1;\n</code></pre>",
+			want: "```js\nvar x = 1;\n```",
+		},
+		{
+			name: "code block with fence inside",
+			body: "<pre><code>```\nx\n```</code></pre>",
+			want: "````\n```\nx\n```\n````",
+		},
+		{
+			name: "ordered list",
+			body: `<ol start="3"><li><p>one</p><pre><code>code</code></pre></li><li>two</li></ol>`,
+			want: "3. one\n\n   ```\n   code\n   ```\n4. two",
+		},
+		{
+			name: "nested list",
+			body: `<ul><li>a<ul><li>b</li></ul></li></ul>`,
+			want: "- a\n  - b",
+		},
+		{
+			name: "blockquote",
+			body: `<blockquote><p>quote</p><p>two</p></blockquote>`,
+			want: "> quote\n>\n> two",
+		},
+		{
+			name: "heading",
+			body: `<h2>Head <code>x</code></h2>`,
+			want: "## Head `x`",
+		},
+		{
+			name: "table",
+			body: `<table><thead><tr><th style="text-align: left;">A</th><th>B|c</th></tr></thead><tbody><tr><td>1</td><td><code>x|y</code></td></tr></tbody></table>`,
+			want: "| A | B\\|c |\n| :--- | --- |\n| 1 | `x\\|y` |",
+		},
+		{
+			name: "escaping",
+			body: `<p># not a heading with *stars* and [brackets]</p>`,
+			want: `\# not a heading with \*stars\* and \[brackets\]`,
+		},
+		{
+			name: "ordered list lookalikes",
+			body: "<p>1. not a list</p><p>2019. was a year</p><p>3) also not</p><p>line<br>4. after break</p>",
+			want: "1\\. not a list\n\n2019\\. was a year\n\n3\\) also not\n\nline  \n4\\. after break",
+		},
+		{
+			name: "number without delimiter",
+			body: "<p>1.5 is a number</p><p>- dash</p><p>-not a list</p>",
+			want: "1.5 is a number\n\n\\- dash\n\n-not a list",
+		},
+		{
+			name: "line break in heading",
+			body: "<h2>a<br>b</h2>",
+			want: "## a b",
+		},
+		{
+			name: "strikethrough and entity lookalikes",
+			body: "<p>~~x~~ &amp;copy; Q&amp;A</p>",
+			want: `\~\~x\~\~ \&copy; Q\&A`,
+		},
+		{
+			name: "relative link",
+			body: `<p><a href="/questions/1"></a> and <a href="x">x</a></p>`,
+			want: "[/questions/1](/questions/1) and [x](x)",
+		},
+		{
+			name: "unknown tags",
+			body: `<p>press <kbd>Ctrl</kbd> <unknown>here</unknown><br>next</p><hr>`,
+			want: "press <kbd>Ctrl</kbd> here  \nnext\n\n---",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Markdown(tt.body); got != tt.want {
+				t.Fatalf("\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	conf := &Config{
+		QuestionNum:  2,
+		ShowQuestion: true,
+		AnswerNum:    1,
+	}
+	results, err := GetResults(conf, fileSearcher{}, fetchStackOverflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = RenderMarkdown(&buf, conf, results); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "\033[") {
+		t.Fatal("markdown output contains escape sequences")
+	}
+	if strings.Count(out, "\n## [") != len(results)-1 || strings.Count(out, "### [Answer]") != len(results) {
+		t.Fatalf("unexpected markdown structure:\n%s", out)
+	}
+}
@@ -0,0 +1,53 @@
+package goso
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// forEach calls fn for every index in [0, n) running at most workers calls at once.
+// After the first failure the calls that have not started yet are skipped and
+// ctx passed to running ones is canceled. It returns all errors joined.
+func forEach(ctx context.Context, n, workers int, fn func(ctx context.Context, i int) error) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, max(workers, 1))
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(ctx, i); err != nil {
+				mu.Lock()
+				// calls aborted because of another failure or ctx add nothing new
+				if len(errs) == 0 || ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		// calls skipped because the parent context is done
+		return context.Cause(parent)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,64 @@
+package goso
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEach(t *testing.T) {
+	var running, peak, calls atomic.Int32
+	err := forEach(context.Background(), 20, 3, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 20 {
+		t.Fatalf("expected 20 calls, got %d", calls.Load())
+	}
+	if peak.Load() > 3 {
+		t.Fatalf("expected at most 3 concurrent calls, got %d", peak.Load())
+	}
+}
+
+func TestForEachError(t *testing.T) {
+	errFailed := errors.New("failed")
+	var calls atomic.Int32
+	err := forEach(context.Background(), 100, 2, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		if i == 1 {
+			return errFailed
+		}
+		// running calls are canceled after the failure
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+			return nil
+		}
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected %v, got %v", errFailed, err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled calls should not be reported: %v", err)
+	}
+	if calls.Load() == 100 {
+		t.Fatal("calls after the failure were not skipped")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = forEach(ctx, 10, 2, func(ctx context.Context, i int) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
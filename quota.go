@@ -0,0 +1,183 @@
+package goso
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// googleLimitDefault is the number of free daily queries of Google Custom Search API
+	googleLimitDefault = 100
+	// quotaWarnRatio is the share of daily quota left when user gets warned
+	quotaWarnRatio = 0.1
+)
+
+// googleQuotaZone is the time zone Google resets daily quota in.
+var googleQuotaZone = func() *time.Location {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		return time.FixedZone("PT", -8*60*60)
+	}
+	return loc
+}()
+
+// Quota is API usage of the current day.
+type Quota struct {
+	StackExchange StackExchangeQuota `json:"stackexchange"`
+	Google        GoogleQuota        `json:"google"`
+}
+
+// StackExchangeQuota is daily quota reported by Stack Exchange API, it is reset at midnight UTC.
+type StackExchangeQuota struct {
+	Date      string `json:"date"`
+	Remaining int    `json:"remaining"`
+	Max       int    `json:"max"`
+	// Requests is the number of requests made by goso
+	Requests int `json:"requests"`
+}
+
+// GoogleQuota is the number of Google Custom Search API queries, the quota is reset at midnight Pacific Time.
+type GoogleQuota struct {
+	Date    string `json:"date"`
+	Queries int    `json:"queries"`
+	Limit   int    `json:"-"`
+}
+
+// Warnings returns messages about quotas close to exhaustion.
+func (q *Quota) Warnings() []string {
+	var warnings []string
+	se := q.StackExchange
+	if se.Max > 0 && float64(se.Remaining) <= float64(se.Max)*quotaWarnRatio {
+		warnings = append(warnings, fmt.Sprintf("Stack Exchange API quota is almost exhausted: %d of %d requests left today", se.Remaining, se.Max))
+	}
+	g := q.Google
+	if g.Limit > 0 && float64(g.Limit-g.Queries) <= float64(g.Limit)*quotaWarnRatio {
+		warnings = append(warnings, fmt.Sprintf("Google Custom Search API quota is almost exhausted: %d of %d queries left today", max(g.Limit-g.Queries, 0), g.Limit))
+	}
+	return warnings
+}
+
+// QuotaTracker persists daily usage of API quotas in a file, so it is tracked across runs.
+// It is safe for concurrent use.
+type QuotaTracker struct {
+	Path string
+	// GoogleLimit is the daily limit of Google Custom Search API queries (default 100)
+	GoogleLimit int
+
+	mu sync.Mutex
+}
+
+// DefaultQuotaPath returns quota.json inside goso directory of user config directory
+// ($XDG_CONFIG_HOME or ~/.config on Linux).
+func DefaultQuotaPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goso", "quota.json"), nil
+}
+
+// Usage returns API usage of the current day.
+func (t *QuotaTracker) Usage() (*Quota, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	q, err := t.load(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// load reads usage of the day of now. It returns usable Quota along with
+// an error, so that unreadable file does not prevent tracking.
+func (t *QuotaTracker) load(now time.Time) (*Quota, error) {
+	q := &Quota{}
+	data, err := os.ReadFile(t.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	} else if err == nil {
+		if err = json.Unmarshal(data, q); err != nil {
+			q = &Quota{}
+			err = fmt.Errorf("failed parsing %s: %w", t.Path, err)
+		}
+	}
+	if day := now.UTC().Format(time.DateOnly); q.StackExchange.Date != day {
+		q.StackExchange = StackExchangeQuota{Date: day}
+	}
+	if day := now.In(googleQuotaZone).Format(time.DateOnly); q.Google.Date != day {
+		q.Google = GoogleQuota{Date: day}
+	}
+	q.Google.Limit = cmp.Or(t.GoogleLimit, googleLimitDefault)
+	return q, err
+}
+
+func (t *QuotaTracker) save(q *Quota) error {
+	dir := filepath.Dir(t.Path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(t.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), t.Path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (t *QuotaTracker) update(res *http.Response, fn func(q *Quota)) {
+	// responses served from cache do not use quota
+	if t == nil || res.Header.Get("X-Goso-Cache") == "hit" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// corrupted file is overwritten with usage counted anew
+	q, _ := t.load(time.Now())
+	fn(q)
+	// failing to track quota should not fail the request
+	_ = t.save(q)
+}
+
+// recordStackExchange stores quota reported by Stack Exchange API response.
+func (t *QuotaTracker) recordStackExchange(res *http.Response, remaining, maxQuota int) {
+	t.update(res, func(q *Quota) {
+		q.StackExchange.Requests++
+		if maxQuota <= 0 {
+			return
+		}
+		// responses of concurrent requests may come out of order
+		if q.StackExchange.Max == maxQuota {
+			remaining = min(remaining, q.StackExchange.Remaining)
+		}
+		q.StackExchange.Remaining = remaining
+		q.StackExchange.Max = maxQuota
+	})
+}
+
+// recordGoogle counts query to Google Custom Search API.
+func (t *QuotaTracker) recordGoogle(res *http.Response) {
+	t.update(res, func(q *Quota) {
+		q.Google.Queries++
+	})
+}
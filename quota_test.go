@@ -0,0 +1,66 @@
+package goso
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQuotaTracker(t *testing.T) {
+	remaining := 5000
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining--
+		fmt.Fprintf(w, `{"items": [], "quota_max": 10000, "quota_remaining": %d}`, remaining)
+	}))
+	t.Cleanup(ts.Close)
+	dir := t.TempDir()
+	tracker := &QuotaTracker{Path: filepath.Join(dir, "quota.json"), GoogleLimit: 10}
+	conf := &Config{
+		Query:            "sort maps",
+		QuestionNum:      1,
+		StackExchangeAPI: ts.URL,
+		Client:           &http.Client{Transport: &CacheTransport{Cache: &Cache{Dir: dir, TTL: time.Hour}}},
+		Quota:            tracker,
+	}
+	for range 2 {
+		// the second round is served from cache and does not use quota
+		if _, err := FetchStackExchange(conf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q, err := tracker.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// search and similar requests
+	want := StackExchangeQuota{Date: time.Now().UTC().Format(time.DateOnly), Remaining: 4998, Max: 10000, Requests: 2}
+	if q.StackExchange != want {
+		t.Fatalf("expected %+v, got %+v", want, q.StackExchange)
+	}
+	if warnings := q.Warnings(); len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	for range 9 {
+		tracker.recordGoogle(&http.Response{Header: http.Header{}})
+	}
+	tracker.recordStackExchange(&http.Response{Header: http.Header{}}, 900, 10000)
+	if q, err = tracker.Usage(); err != nil {
+		t.Fatal(err)
+	}
+	if q.Google.Queries != 9 || q.StackExchange.Remaining != 900 {
+		t.Fatalf("unexpected usage: %+v", q)
+	}
+	if warnings := q.Warnings(); len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	q, err = tracker.load(time.Now().Add(48 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.StackExchange.Requests != 0 || q.Google.Queries != 0 {
+		t.Fatalf("expected usage to reset next day, got %+v", q)
+	}
+}
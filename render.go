@@ -0,0 +1,430 @@
+package goso
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"golang.org/x/term"
+)
+
+// renderer holds per-call rendering state, so concurrent calls do not interfere.
+type renderer struct {
+	width     int
+	plain     bool
+	formatter chroma.Formatter
+	lexer     chroma.Lexer // overrides language detection when set
+	style     *chroma.Style
+	tags      []string // tags of the question being rendered
+	number    bool     // label code blocks with their numbers
+	codeBlock int      // the number of code blocks rendered so far
+}
+
+func newRenderer(conf *Config) (*renderer, error) {
+	rd := &renderer{width: terminalDefaultWidth}
+	if conf.Width > 0 {
+		rd.width = max(conf.Width, terminalMinWidth)
+	} else if fd := int(os.Stdout.Fd()); term.IsTerminal(fd) {
+		width, _, err := term.GetSize(fd)
+		if err != nil {
+			return nil, err
+		}
+		rd.width = max(width, terminalMinWidth)
+	}
+	rd.number = conf.NumberCodeBlocks
+	rd.style = styles.Get(conf.Style)
+	if rd.style == nil {
+		rd.style = styles.Fallback
+	}
+	rd.formatter = formatters.Get("terminal16m")
+	if rd.formatter == nil {
+		rd.formatter = formatters.Fallback
+	}
+	if conf.Lexer != "" {
+		rd.lexer = lexers.Get(conf.Lexer)
+		if rd.lexer == nil {
+			rd.lexer = lexers.Fallback
+		}
+	}
+	return rd, nil
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// plainText converts HTML body to text without any escape sequences.
+func plainText(body string) (string, error) {
+	rd := &renderer{plain: true}
+	var sb strings.Builder
+	if err := rd.renderBody(body, &sb); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// sgr returns escape sequence code unless renderer produces plain text.
+func (rd *renderer) sgr(code string) string {
+	if rd.plain {
+		return ""
+	}
+	return code
+}
+
+// renderBody writes HTML body of a question or an answer to sb.
+func (rd *renderer) renderBody(body string, sb *strings.Builder) error {
+	out, err := rd.blocks(parseDocument(body).children, rd.width)
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		sb.WriteString(out)
+		sb.WriteString("\n")
+	}
+	return nil
+}
+
+// blocks renders nodes wrapping text to width columns, zero width disables wrapping.
+func (rd *renderer) blocks(nodes []*node, width int) (string, error) {
+	blocks := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		b, err := rd.block(n, width)
+		if err != nil {
+			return "", err
+		}
+		if b != "" {
+			blocks = append(blocks, b)
+		}
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+func (rd *renderer) block(n *node, width int) (string, error) {
+	switch n.kind {
+	case paragraph:
+		return wrapText(rd.inlines(n.children), width), nil
+	case heading:
+		return wrapText(rd.inlines([]*node{{kind: strong, children: n.children}}), width), nil
+	case codeBlock:
+		rd.codeBlock++
+		code, err := rd.highlight(n.text, n.attr)
+		if err != nil || !rd.number {
+			return code, err
+		}
+		return fmt.Sprintf("%s[code %d]%s\n%s", rd.sgr(gray), rd.codeBlock, rd.sgr(reset), code), nil
+	case blockquote:
+		prefix := rd.sgr(gray) + "│ " + rd.sgr(reset)
+		if rd.plain {
+			prefix = "> "
+		}
+		content, err := rd.blocks(n.children, indentWidth(width, 2))
+		if err != nil {
+			return "", err
+		}
+		return prefixLines(content, prefix, strings.TrimRight(prefix, " ")), nil
+	case list:
+		items := make([]string, 0, len(n.children))
+		for i, item := range n.children {
+			marker := " - "
+			if n.ordered {
+				marker = fmt.Sprintf(" %d. ", n.level+i)
+			}
+			content, err := rd.blocks(item.children, indentWidth(width, len(marker)))
+			if err != nil {
+				return "", err
+			}
+			indent := strings.Repeat(" ", len(marker))
+			items = append(items, marker+strings.TrimPrefix(prefixLines(content, indent, ""), indent))
+		}
+		return strings.Join(items, "\n"), nil
+	case table:
+		return rd.table(n), nil
+	case rule:
+		return rd.sgr(gray) + strings.Repeat("─", cmp.Or(width, terminalDefaultWidth)) + rd.sgr(reset), nil
+	}
+	return wrapText(rd.inlines([]*node{n}), width), nil
+}
+
+// indentWidth returns width left for content indented by indent columns.
+func indentWidth(width, indent int) int {
+	if width == 0 {
+		return 0
+	}
+	// keep some room for text in deeply nested blocks
+	return max(width-indent, terminalMinWidth)
+}
+
+// tagLexers maps popular tags that are not names of chroma lexers.
+var tagLexers = map[string]string{
+	"golang":       "go",
+	"reactjs":      "react",
+	"django":       "python",
+	"flask":        "python",
+	"pandas":       "python",
+	"numpy":        "python",
+	"spring":       "java",
+	"android":      "java",
+	"jquery":       "javascript",
+	"angular":      "typescript",
+	"linux":        "bash",
+	"shell":        "bash",
+	"command-line": "bash",
+}
+
+// lexerFor picks lexer for code block: explicit override, language hint of the block,
+// tags of the question, content analysis and plain text in that order.
+func (rd *renderer) lexerFor(code, lang string) chroma.Lexer {
+	if rd.lexer != nil {
+		return rd.lexer
+	}
+	if lang == "none" {
+		return lexers.Fallback
+	}
+	if lang != "" {
+		if lexer := lexers.Get(lang); lexer != nil {
+			return lexer
+		}
+	}
+	for _, tag := range rd.tags {
+		if lexer := lexerForTag(tag); lexer != nil {
+			return lexer
+		}
+	}
+	if lexer := lexers.Analyse(code); lexer != nil {
+		return lexer
+	}
+	return lexers.Fallback
+}
+
+func lexerForTag(tag string) chroma.Lexer {
+	if name, ok := tagLexers[tag]; ok {
+		return lexers.Get(name)
+	}
+	if lexer := lexers.Get(tag); lexer != nil {
+		return lexer
+	}
+	// versioned tags like python-3.x or c++11
+	base, _, found := strings.Cut(tag, "-")
+	if !found {
+		base = strings.TrimRight(tag, "0123456789.")
+	}
+	if base == tag || base == "" {
+		return nil
+	}
+	return lexerForTag(base)
+}
+
+// highlight formats code block with chroma.
+func (rd *renderer) highlight(code, lang string) (string, error) {
+	if rd.plain {
+		return code, nil
+	}
+	iterator, err := rd.lexerFor(code, lang).Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	err = rd.formatter.Format(&sb, rd.style, iterator)
+	if err != nil {
+		return "", err
+	}
+	out := sb.String()
+	// lexers ensure code ends with newline, blocks are separated by renderer
+	if idx := strings.LastIndex(out, "\n"); idx != -1 && ansiPattern.ReplaceAllString(out[idx+1:], "") == "" {
+		out = out[:idx] + out[idx+1:]
+	}
+	return out, nil
+}
+
+func (rd *renderer) table(t *node) string {
+	rows := make([][]string, len(t.children))
+	var widths []int
+	for i, row := range t.children {
+		rows[i] = make([]string, len(row.children))
+		for j, cell := range row.children {
+			children := cell.children
+			if cell.header {
+				children = []*node{{kind: strong, children: children}}
+			}
+			rows[i][j] = strings.ReplaceAll(rd.inlines(children), "\n", " ")
+			if j == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j] = max(widths[j], visibleWidth(rows[i][j]))
+		}
+	}
+	sep := rd.sgr(gray) + " │ " + rd.sgr(reset)
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		var sb strings.Builder
+		for j, cell := range row {
+			if j > 0 {
+				sb.WriteString(sep)
+			}
+			sb.WriteString(cell)
+			if j < len(row)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[j]-visibleWidth(cell)))
+			}
+		}
+		lines = append(lines, sb.String())
+		if i == 0 && len(t.children[0].children) > 0 && t.children[0].children[0].header {
+			parts := make([]string, len(widths))
+			for j, w := range widths {
+				parts[j] = strings.Repeat("─", w)
+			}
+			lines = append(lines, rd.sgr(gray)+strings.Join(parts, "─┼─")+rd.sgr(reset))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineWriter keeps track of active text styles, so closing nested
+// element restores styles of its parents.
+type inlineWriter struct {
+	rd    *renderer
+	sb    strings.Builder
+	stack []string
+}
+
+func (w *inlineWriter) push(code string) {
+	if w.rd.plain {
+		return
+	}
+	w.stack = append(w.stack, code)
+	w.sb.WriteString(code)
+}
+
+func (w *inlineWriter) pop() {
+	if w.rd.plain {
+		return
+	}
+	w.stack = w.stack[:len(w.stack)-1]
+	w.sb.WriteString(reset)
+	for _, code := range w.stack {
+		w.sb.WriteString(code)
+	}
+}
+
+func (rd *renderer) inlines(nodes []*node) string {
+	w := &inlineWriter{rd: rd}
+	w.write(nodes)
+	lines := strings.Split(w.sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (w *inlineWriter) styled(code string, children []*node) {
+	w.push(code)
+	w.write(children)
+	w.pop()
+}
+
+func (w *inlineWriter) write(nodes []*node) {
+	for _, n := range nodes {
+		switch n.kind {
+		case text:
+			w.sb.WriteString(n.text)
+		case emphasis:
+			w.styled(italic, n.children)
+		case strong, keyboard:
+			w.styled(bold, n.children)
+		case strike:
+			w.styled(strikethrough, n.children)
+		case inlineCode, codeBlock:
+			w.push(green)
+			w.sb.WriteString(n.text)
+			w.pop()
+		case link:
+			label := strings.TrimSpace(plainInlines(n.children))
+			if label != "" && label != n.attr {
+				w.write(n.children)
+			}
+			if n.attr != "" {
+				if label != "" && label != n.attr {
+					w.sb.WriteString(" ")
+				}
+				w.push(urlColor)
+				w.sb.WriteString(n.attr)
+				w.pop()
+			}
+		case image:
+			w.push(urlColor)
+			w.sb.WriteString(strings.TrimSpace("[image] " + n.text + " " + n.attr))
+			w.pop()
+		case lineBreak:
+			w.sb.WriteString("\n")
+		case rule:
+		default:
+			w.write(n.children)
+		}
+	}
+}
+
+// plainInlines returns text content of inline nodes.
+func plainInlines(nodes []*node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		switch n.kind {
+		case text, inlineCode, codeBlock:
+			sb.WriteString(n.text)
+		default:
+			sb.WriteString(plainInlines(n.children))
+		}
+	}
+	return sb.String()
+}
+
+// RenderResults formats results for the terminal with syntax highlighted code blocks.
+// It is safe for concurrent use.
+func RenderResults(conf *Config, results []*Result) (string, error) {
+	return RenderResultsContext(context.Background(), conf, results)
+}
+
+// RenderResultsContext is like RenderResults but stops when ctx is done.
+func RenderResultsContext(ctx context.Context, conf *Config, results []*Result) (string, error) {
+	rd, err := newRenderer(conf)
+	if err != nil {
+		return "", err
+	}
+	var answers strings.Builder
+	for _, res := range results {
+		if err = ctx.Err(); err != nil {
+			return "", err
+		}
+		if _, err = rd.renderResult(res, conf.ShowQuestion, &answers); err != nil {
+			return "", err
+		}
+	}
+	return answers.String(), nil
+}
+
+// renderResult writes result with its answers to sb. It returns offsets
+// in sb where headers of the answers start.
+func (rd *renderer) renderResult(res *Result, showQuestion bool, sb *strings.Builder) ([]int, error) {
+	rd.tags = res.Tags
+	sb.WriteString(res.header(rd.width))
+	if showQuestion {
+		var question strings.Builder
+		if err := rd.renderBody(res.Body, &question); err != nil {
+			return nil, err
+		}
+		sb.WriteString("\n\n")
+		sb.WriteString(question.String())
+	}
+	offsets := make([]int, len(res.Answers))
+	for i, ans := range res.Answers {
+		offsets[i] = sb.Len()
+		sb.WriteString(ans.header(rd.width))
+		if err := rd.renderBody(ans.Body, sb); err != nil {
+			return nil, err
+		}
+	}
+	return offsets, nil
+}
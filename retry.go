@@ -0,0 +1,250 @@
+package goso
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	retryBaseDelayDefault = 500 * time.Millisecond
+	retryMaxDelayDefault  = 30 * time.Second
+	// Stack Exchange reports throttling with this error_id inside HTTP 400 response
+	throttleViolation = 502
+)
+
+var throttlePattern = regexp.MustCompile(`available in (\d+) seconds`)
+
+// RetryPolicy controls retries of failed API requests. It also keeps track of
+// backoff requested by Stack Exchange API for each API method, so one policy
+// should be shared by all requests. It is safe for concurrent use.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts of each request, values below 2 disable retries
+	Attempts int
+	// BaseDelay is the delay before the first retry, doubled for every next one (default 500ms)
+	BaseDelay time.Duration
+	// MaxDelay limits a single delay, requests asked to wait longer are not retried (default 30s)
+	MaxDelay time.Duration
+	// Jitter is the fraction of delay in [0, 1] randomized to spread retries of concurrent requests
+	Jitter float64
+
+	mu      sync.Mutex
+	backoff map[string]time.Time // API method -> time it can be called again
+}
+
+// get sends GET request to url retrying it according to conf.Retry.
+// Returned response always has Request set.
+func (c *Config) get(ctx context.Context, url string) (*http.Response, error) {
+	p := c.Retry
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err = p.wait(ctx, req); err != nil {
+			return nil, err
+		}
+		res, err := c.Client.Do(req)
+		if res != nil && res.Request == nil {
+			res.Request = req
+		}
+		delay, retry := p.retry(ctx, res, err, attempt)
+		if !retry {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		if err = sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retry reports whether failed request should be retried and how long to wait before it.
+func (p *RetryPolicy) retry(ctx context.Context, res *http.Response, err error, attempt int) (time.Duration, bool) {
+	if p == nil || attempt >= p.Attempts || ctx.Err() != nil {
+		return 0, false
+	}
+	delay := p.delay(attempt)
+	if err != nil {
+		return delay, retryable(err)
+	}
+	wait, ok := retryAfter(res)
+	switch {
+	case slices.Contains([]int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}, res.StatusCode):
+	case res.StatusCode == http.StatusBadRequest:
+		var throttled bool
+		wait, throttled = throttleDelay(res)
+		if !throttled {
+			return 0, false
+		}
+		ok = true
+	default:
+		return 0, false
+	}
+	if ok {
+		if wait > p.maxDelay() {
+			return 0, false
+		}
+		delay = max(delay, wait)
+	}
+	return delay, true
+}
+
+// retryable reports whether transport error may go away on retry: timeouts, temporary DNS
+// failures and connections reset or refused. Invalid certificates, unknown hosts and
+// proxy failures will not, so they are returned at once.
+func retryable(err error) bool {
+	var (
+		certErr *tls.CertificateVerificationError
+		dnsErr  *net.DNSError
+		netErr  net.Error
+	)
+	switch {
+	case errors.Is(err, ErrCacheMiss), errors.As(err, &certErr):
+		return false
+	case errors.As(err, &dnsErr):
+		return !dnsErr.IsNotFound && (dnsErr.IsTimeout || dnsErr.IsTemporary)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		// server closed connection without response
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// delay returns exponential delay before retry following attempt.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := cmp.Or(p.BaseDelay, retryBaseDelayDefault)
+	for i := 1; i < attempt && d < p.maxDelay(); i++ {
+		d *= 2
+	}
+	d = min(d, p.maxDelay())
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		d -= time.Duration(rand.Float64() * jitter * float64(d))
+	}
+	return d
+}
+
+func (p *RetryPolicy) maxDelay() time.Duration {
+	return cmp.Or(p.MaxDelay, retryMaxDelayDefault)
+}
+
+// setBackoff records backoff field of Stack Exchange response, the API
+// requires clients to wait that many seconds before calling the same method again.
+func (p *RetryPolicy) setBackoff(res *http.Response, seconds int) {
+	if p == nil || seconds <= 0 || res.Header.Get("X-Goso-Cache") == "hit" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.backoff == nil {
+		p.backoff = make(map[string]time.Time)
+	}
+	p.backoff[apiMethod(res.Request)] = time.Now().Add(time.Duration(seconds) * time.Second)
+}
+
+// wait blocks until backoff of the method called by req is over.
+func (p *RetryPolicy) wait(ctx context.Context, req *http.Request) error {
+	if p == nil {
+		return nil
+	}
+	method := apiMethod(req)
+	p.mu.Lock()
+	until, ok := p.backoff[method]
+	if ok && time.Now().After(until) {
+		delete(p.backoff, method)
+	}
+	p.mu.Unlock()
+	return sleep(ctx, time.Until(until))
+}
+
+// apiMethod returns request path with lists of IDs replaced by placeholder,
+// so that /questions/1;2/answers and /questions/3/answers are the same method.
+func apiMethod(req *http.Request) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for i, seg := range segments {
+		if seg != "" && strings.Trim(seg, "0123456789;") == "" {
+			segments[i] = "{ids}"
+		}
+	}
+	return req.URL.Host + strings.Join(segments, "/")
+}
+
+// retryAfter parses Retry-After header given either in seconds or as HTTP date.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if date, err := http.ParseTime(v); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// throttleDelay reports whether response is Stack Exchange throttle_violation error
+// and returns the time left until requests are accepted again.
+// The body of response is left unread.
+func throttleDelay(res *http.Response) (time.Duration, bool) {
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), res.Body), res.Body}
+	if err != nil {
+		return 0, false
+	}
+	var envelope stackExchangeEnvelope
+	if json.Unmarshal(data, &envelope) != nil || envelope.ErrorID != throttleViolation {
+		return 0, false
+	}
+	var wait time.Duration
+	if m := throttlePattern.FindStringSubmatch(envelope.ErrorMessage); m != nil {
+		seconds, _ := strconv.Atoi(m[1])
+		wait = time.Duration(seconds) * time.Second
+	}
+	return wait, true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,261 @@
+package goso
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const searchResponse = `{"items": [
+	{"tags": ["go"], "question_id": 1, "score": 5, "answer_count": 2, "creation_date": 1700000000,
+	 "link": "https://stackoverflow.com/questions/1/sort-maps", "title": "Sort maps"}
+], "backoff": %d}`
+
+// newFlakyServer returns server failing the first failures requests with fail
+// and counting all requests it receives.
+func newFlakyServer(t *testing.T, failures int32, fail http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var count atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if count.Add(1) <= failures {
+			fail(w, r)
+			return
+		}
+		fmt.Fprintf(w, searchResponse, 0)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &count
+}
+
+func retryConfig(ts *httptest.Server, attempts int) *Config {
+	return &Config{
+		Query:            "sort maps",
+		QuestionNum:      1,
+		StackExchangeAPI: ts.URL,
+		Client:           ts.Client(),
+		Retry:            &RetryPolicy{Attempts: attempts, BaseDelay: time.Millisecond, Jitter: 0.5},
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		fail     http.HandlerFunc
+		ok       bool
+		requests int32
+	}{
+		{
+			name:     "server error",
+			attempts: 3,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			},
+			ok:       true,
+			requests: 3,
+		},
+		{
+			name:     "attempts exhausted",
+			attempts: 2,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+			},
+			requests: 2,
+		},
+		{
+			name:     "retries disabled",
+			attempts: 0,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			},
+			requests: 1,
+		},
+		{
+			name:     "connection reset",
+			attempts: 3,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			},
+			ok:       true,
+			requests: 3,
+		},
+		{
+			name:     "retry after",
+			attempts: 3,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+			},
+			ok:       true,
+			requests: 3,
+		},
+		{
+			name:     "retry after too long",
+			attempts: 3,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "3600")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+			},
+			requests: 1,
+		},
+		{
+			name:     "throttle violation",
+			attempts: 3,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error_id": 502, "error_name": "throttle_violation",
+					"error_message": "too many requests from this IP, more requests available in 0 seconds"}`)
+			},
+			ok:       true,
+			requests: 3,
+		},
+		{
+			name:     "throttle violation too long",
+			attempts: 3,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error_id": 502, "error_name": "throttle_violation",
+					"error_message": "too many requests from this IP, more requests available in 82495 seconds"}`)
+			},
+			requests: 1,
+		},
+		{
+			name:     "bad request",
+			attempts: 3,
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error_id": 400, "error_name": "bad_parameter", "error_message": "site is required"}`)
+			},
+			requests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, count := newFlakyServer(t, 2, tt.fail)
+			results, err := FetchStackExchange(retryConfig(ts, tt.attempts))
+			if tt.ok && (err != nil || len(results) != 1) {
+				t.Fatalf("expected 1 result, got %d: %v", len(results), err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected error")
+			}
+			if got := count.Load(); got != tt.requests {
+				t.Fatalf("expected %d requests, got %d", tt.requests, got)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var count atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		fmt.Fprintf(w, searchResponse, 10)
+	}))
+	t.Cleanup(ts.Close)
+	conf := retryConfig(ts, 3)
+	if _, err := FetchStackExchange(conf); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// /search/advanced asked to back off, the request must wait instead of hitting the server
+	_, err := FetchStackExchangeContext(ctx, conf)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if got := count.Load(); got != 1 {
+		t.Fatalf("expected 1 request, got %d", got)
+	}
+	// other methods are not affected
+	results := map[int]*Result{1: {QuestionId: 1}}
+	if err = FetchStackOverflow(conf, results); err != nil {
+		t.Fatal(err)
+	}
+	// answers and questions
+	if got := count.Load(); got != 3 {
+		t.Fatalf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if got := p.delay(attempt + 1); got != want*time.Millisecond {
+			t.Fatalf("attempt %d: expected %v, got %v", attempt+1, want*time.Millisecond, got)
+		}
+	}
+	p.Jitter = 0.5
+	for range 100 {
+		if got := p.delay(2); got <= 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("jittered delay %v is out of range", got)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	dial := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://api.stackexchange.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", dial(os.NewSyscallError("connect", syscall.ECONNREFUSED)), true},
+		{"connection reset", dial(os.NewSyscallError("read", syscall.ECONNRESET)), true},
+		{"timeout", dial(os.ErrDeadlineExceeded), true},
+		{"closed connection", &url.Error{Op: "Get", URL: "https://api.stackexchange.com", Err: io.EOF}, true},
+		{"temporary DNS failure", dial(&net.DNSError{Err: "server misbehaving", IsTemporary: true}), true},
+		{"unknown host", dial(&net.DNSError{Err: "no such host", IsNotFound: true}), false},
+		{"untrusted certificate", dial(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), false},
+		{"proxy authentication", &url.Error{Op: "Get", URL: "https://api.stackexchange.com",
+			Err: &net.OpError{Op: "proxyconnect", Net: "tcp", Err: errors.New("Proxy Authentication Required")}}, false},
+		{"cache miss", ErrCacheMiss, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Fatalf("expected %v, got %v for %v", tt.want, got, tt.err)
+			}
+		})
+	}
+}
+
+func TestRetryCertificate(t *testing.T) {
+	var conns atomic.Int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, searchResponse, 0)
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	conf := retryConfig(ts, 3)
+	// certificate of test server is not trusted by default client
+	conf.Client = &http.Client{}
+	if _, err := FetchStackExchange(conf); err == nil {
+		t.Fatal("expected certificate error")
+	}
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("expected 1 connection, got %d", got)
+	}
+}
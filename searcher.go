@@ -0,0 +1,151 @@
+package goso
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Capability describes what kind of metadata a Searcher is able to provide.
+type Capability uint
+
+const (
+	// CapScores means results carry upvote counts and creation dates,
+	// so they can be reordered by score.
+	CapScores Capability = 1 << iota
+)
+
+func (c Capability) Has(flag Capability) bool {
+	return c&flag == flag
+}
+
+// Searcher discovers Stack Overflow questions relevant to Config.Query.
+// Search returns question references ordered by rank, best match first;
+// only Title, Link and QuestionId are required to be populated.
+type Searcher interface {
+	Name() string
+	Capabilities() Capability
+	// Configured reports why the searcher cannot be used with conf, or nil if it can.
+	Configured(conf *Config) error
+	Search(ctx context.Context, conf *Config) ([]*Result, error)
+}
+
+var (
+	searchersMu sync.RWMutex
+	searchers   []Searcher
+)
+
+func init() {
+	RegisterSearcher(OpenSerpSearcher{})
+	RegisterSearcher(GoogleSearcher{})
+	RegisterSearcher(StackExchangeSearcher{})
+}
+
+// RegisterSearcher adds s to the registry. Searchers registered earlier take
+// priority in SelectSearcher. Registering a name twice replaces the previous searcher.
+func RegisterSearcher(s Searcher) {
+	searchersMu.Lock()
+	defer searchersMu.Unlock()
+	idx := slices.IndexFunc(searchers, func(r Searcher) bool { return r.Name() == s.Name() })
+	if idx != -1 {
+		searchers[idx] = s
+		return
+	}
+	searchers = append(searchers, s)
+}
+
+// LookupSearcher returns the registered searcher with the given name.
+func LookupSearcher(name string) (Searcher, bool) {
+	searchersMu.RLock()
+	defer searchersMu.RUnlock()
+	idx := slices.IndexFunc(searchers, func(s Searcher) bool { return s.Name() == name })
+	if idx == -1 {
+		return nil, false
+	}
+	return searchers[idx], true
+}
+
+// Searchers returns the names of all registered searchers in priority order.
+func Searchers() []string {
+	searchersMu.RLock()
+	defer searchersMu.RUnlock()
+	names := make([]string, len(searchers))
+	for i, s := range searchers {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// SelectSearcher returns the searcher named name or, if name is empty,
+// the first registered searcher that is configured for conf.
+func SelectSearcher(name string, conf *Config) (Searcher, error) {
+	if name != "" {
+		s, ok := LookupSearcher(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown search engine %q", name)
+		}
+		if err := s.Configured(conf); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		return s, nil
+	}
+	searchersMu.RLock()
+	defer searchersMu.RUnlock()
+	for _, s := range searchers {
+		if s.Configured(conf) == nil {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no search engine is configured")
+}
+
+type GoogleSearcher struct{}
+
+func (GoogleSearcher) Name() string { return "google" }
+
+func (GoogleSearcher) Capabilities() Capability { return CapScores }
+
+func (GoogleSearcher) Configured(conf *Config) error {
+	if conf.ApiKey == "" {
+		return fmt.Errorf("API key is not set")
+	}
+	if conf.SearchEngine == "" {
+		return fmt.Errorf("search engine ID is not set")
+	}
+	return nil
+}
+
+func (GoogleSearcher) Search(ctx context.Context, conf *Config) ([]*Result, error) {
+	return FetchGoogleContext(ctx, conf)
+}
+
+type OpenSerpSearcher struct{}
+
+func (OpenSerpSearcher) Name() string { return "openserp" }
+
+func (OpenSerpSearcher) Capabilities() Capability { return 0 }
+
+func (OpenSerpSearcher) Configured(conf *Config) error {
+	if conf.OpenSerpHost == "" || conf.OpenSerpPort == 0 {
+		return fmt.Errorf("OpenSerp host and port are not set")
+	}
+	return nil
+}
+
+func (OpenSerpSearcher) Search(ctx context.Context, conf *Config) ([]*Result, error) {
+	return FetchOpenSerpContext(ctx, conf)
+}
+
+// StackExchangeSearcher queries Stack Exchange API directly and needs no setup.
+type StackExchangeSearcher struct{}
+
+func (StackExchangeSearcher) Name() string { return "stackexchange" }
+
+func (StackExchangeSearcher) Capabilities() Capability { return CapScores }
+
+func (StackExchangeSearcher) Configured(conf *Config) error { return nil }
+
+func (StackExchangeSearcher) Search(ctx context.Context, conf *Config) ([]*Result, error) {
+	return FetchStackExchangeContext(ctx, conf)
+}
@@ -0,0 +1,219 @@
+package goso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSelectSearcher(t *testing.T) {
+	// the registry is global, later tests should see the built-in searchers only
+	searchersMu.RLock()
+	registered := slices.Clone(searchers)
+	searchersMu.RUnlock()
+	t.Cleanup(func() {
+		searchersMu.Lock()
+		searchers = registered
+		searchersMu.Unlock()
+	})
+	RegisterSearcher(fileSearcher{})
+	if _, ok := LookupSearcher("file"); !ok {
+		t.Fatal("file searcher is not registered")
+	}
+	if names := Searchers(); !slices.Equal(names, []string{"openserp", "google", "stackexchange", "file"}) {
+		t.Fatalf("unexpected order of searchers: %q", names)
+	}
+	s, err := SelectSearcher("", &Config{ApiKey: "key", SearchEngine: "se"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name() != "google" {
+		t.Fatalf("expected google searcher, got %s", s.Name())
+	}
+	s, err = SelectSearcher("", &Config{OpenSerpHost: "127.0.0.1", OpenSerpPort: 7000, ApiKey: "key", SearchEngine: "se"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name() != "openserp" {
+		t.Fatalf("expected openserp searcher, got %s", s.Name())
+	}
+	if _, err = SelectSearcher("google", &Config{}); err == nil {
+		t.Fatal("expected error for unconfigured google searcher")
+	}
+	if _, err = SelectSearcher("unknown", &Config{}); err == nil {
+		t.Fatal("expected error for unknown searcher")
+	}
+}
+
+func newStackExchangeServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/2.3/search/advanced", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("q") != "sort maps" || q.Get("site") != "stackoverflow" || q.Get("answers") != "1" {
+			http.Error(w, "bad parameter", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"items": [
+			{"tags": ["go", "sorting"], "question_id": 1, "score": 5, "answer_count": 2, "creation_date": 1700000000,
+			 "link": "https://stackoverflow.com/questions/1/sort-maps", "title": "Sort maps &amp; slices"}
+		]}`)
+	})
+	mux.HandleFunc("/2.3/similar", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("title") != "sort maps" {
+			http.Error(w, "bad parameter", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"items": [
+			{"tags": ["go"], "question_id": 1, "score": 5, "answer_count": 2, "creation_date": 1700000000,
+			 "link": "https://stackoverflow.com/questions/1/sort-maps", "title": "Sort maps &amp; slices"},
+			{"tags": ["go"], "question_id": 2, "score": 1, "answer_count": 0, "creation_date": 1700000000,
+			 "link": "https://stackoverflow.com/questions/2/unanswered", "title": "Unanswered"},
+			{"tags": ["go", "maps"], "question_id": 3, "score": 10, "answer_count": 1, "creation_date": 1600000000,
+			 "link": "https://stackoverflow.com/questions/3/iterate-map", "title": "Iterate map in order"}
+		]}`)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestFetchStackExchange(t *testing.T) {
+	ts := newStackExchangeServer(t)
+	conf := &Config{
+		Query:            "sort maps",
+		QuestionNum:      5,
+		StackExchangeAPI: ts.URL + "/2.3",
+		Client:           ts.Client(),
+	}
+	results, err := FetchStackExchange(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	first := results[0]
+	if first.QuestionId != 1 || first.Title != "Sort maps & slices" || first.UpvoteCount != 5 ||
+		first.AnswerCount != 2 || len(first.Tags) != 2 || first.Date.Unix() != 1700000000 {
+		t.Fatalf("unexpected first result: %+v", first)
+	}
+	if results[1].QuestionId != 3 {
+		t.Fatalf("expected question 3 from /similar, got %d", results[1].QuestionId)
+	}
+	conf.QuestionNum = 1
+	results, err = FetchStackExchange(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+}
+
+func TestFetchStackExchangeCanceled(t *testing.T) {
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { close(block) })
+	conf := &Config{
+		Query:            "sort maps",
+		QuestionNum:      5,
+		StackExchangeAPI: ts.URL,
+		Client:           ts.Client(),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := GetResultsContext(ctx, conf, StackExchangeSearcher{}, FetchStackOverflowContext)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+// newGoogleServer returns stand-in of Google Custom Search API serving pages
+// of 10 items, odd items of the first page are not answered when unanswered is set.
+func newGoogleServer(t *testing.T, pages int, unanswered bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		start, err := strconv.Atoi(r.URL.Query().Get("start"))
+		if err != nil || r.URL.Query().Get("key") != "key" || r.URL.Query().Get("cx") != "se" {
+			http.Error(w, "bad parameter", http.StatusBadRequest)
+			return
+		}
+		items := make([]string, 0, googlePageSize)
+		for id := start; id < start+googlePageSize; id++ {
+			answers := 1
+			if unanswered && start == 1 && id%2 == 1 {
+				answers = 0
+			}
+			items = append(items, fmt.Sprintf(`{"title": "Question %d", "link": "https://stackoverflow.com/questions/%d/title",
+				"pagemap": {"question": [{"answercount": "%d", "upvotecount": "1"}]}}`, id, id, answers))
+		}
+		nextPage := ""
+		if start/googlePageSize+1 < pages {
+			nextPage = fmt.Sprintf(`{"startIndex": %d}`, start+googlePageSize)
+		}
+		fmt.Fprintf(w, `{"queries": {"nextPage": [%s]}, "items": [%s]}`, nextPage, strings.Join(items, ","))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+func TestFetchGooglePages(t *testing.T) {
+	tests := []struct {
+		name        string
+		pages       int
+		budget      int
+		questionNum int
+		answered    bool
+		results     int
+		requests    int32
+	}{
+		{name: "first page is enough", pages: 3, questionNum: 5, results: 5, requests: 1},
+		{name: "next page for answered questions", pages: 3, questionNum: 10, results: 15, requests: 2},
+		{name: "budget", pages: 3, budget: 1, questionNum: 10, results: 5, requests: 1},
+		{name: "default budget", pages: 5, questionNum: 30, answered: true, results: 30, requests: 3},
+		{name: "default budget with skipped links", pages: 5, questionNum: 30, results: 35, requests: 4},
+		{name: "budget with skipped links", pages: 5, budget: 3, questionNum: 30, results: 25, requests: 3},
+		{name: "no more pages", pages: 2, budget: 5, questionNum: 30, results: 15, requests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, requests := newGoogleServer(t, tt.pages, !tt.answered)
+			conf := &Config{
+				ApiKey:       "key",
+				SearchEngine: "se",
+				Query:        "sort maps",
+				QuestionNum:  tt.questionNum,
+				GooglePages:  tt.budget,
+				GoogleAPI:    ts.URL,
+				Client:       ts.Client(),
+			}
+			results, err := FetchGoogle(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != tt.results {
+				t.Fatalf("expected %d results, got %d", tt.results, len(results))
+			}
+			if got := requests.Load(); got != tt.requests {
+				t.Fatalf("expected %d requests, got %d", tt.requests, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package goso
+
+import (
+	"slices"
+	"strings"
+)
+
+const defaultSite = "stackoverflow"
+
+// siteDomains maps API parameters of sites that do not live on stackexchange.com to their domains.
+var siteDomains = map[string]string{
+	"stackoverflow": "stackoverflow.com",
+	"superuser":     "superuser.com",
+	"serverfault":   "serverfault.com",
+	"askubuntu":     "askubuntu.com",
+	"stackapps":     "stackapps.com",
+	"mathoverflow":  "mathoverflow.net",
+}
+
+// siteDomain returns domain of Stack Exchange site given by its API parameter
+// (e.g. unix, ru.stackoverflow or meta.superuser) or domain.
+func siteDomain(site string) string {
+	if _, ok := siteFromHost(site); ok {
+		return site
+	}
+	if domain, ok := siteDomains[site]; ok {
+		return domain
+	}
+	// localized and meta sites of the sites above, e.g. ru.stackoverflow.com, meta.superuser.com
+	if prefix, base, ok := strings.Cut(site, "."); ok {
+		if domain, ok := siteDomains[base]; ok {
+			return prefix + "." + domain
+		}
+	}
+	return site + ".stackexchange.com"
+}
+
+// siteFromHost returns API parameter of Stack Exchange site hosted on host,
+// it reports false if host does not belong to Stack Exchange network.
+func siteFromHost(host string) (string, bool) {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	if site, ok := strings.CutSuffix(host, ".stackexchange.com"); ok && site != "" && site != "api" {
+		return site, true
+	}
+	for site, domain := range siteDomains {
+		if host == domain {
+			return site, true
+		}
+		if prefix, ok := strings.CutSuffix(host, "."+domain); ok && !strings.Contains(prefix, ".") {
+			return prefix + "." + site, true
+		}
+	}
+	return "", false
+}
+
+// normalizeSite returns API parameter of site given by its API parameter or domain.
+func normalizeSite(site string) string {
+	if s, ok := siteFromHost(site); ok {
+		return s
+	}
+	return strings.ToLower(site)
+}
+
+// sites returns API parameters of sites to search, stackoverflow by default.
+func (c *Config) sites() []string {
+	if len(c.Sites) == 0 {
+		return []string{defaultSite}
+	}
+	sites := make([]string, 0, len(c.Sites))
+	for _, site := range c.Sites {
+		if site = normalizeSite(strings.TrimSpace(site)); site != "" && !slices.Contains(sites, site) {
+			sites = append(sites, site)
+		}
+	}
+	if len(sites) == 0 {
+		return []string{defaultSite}
+	}
+	return sites
+}
+
+// site returns API parameter of the site result belongs to.
+func (r *Result) site() string {
+	if r.Site == "" {
+		return defaultSite
+	}
+	return r.Site
+}
+
+// siteQuery returns search operators restricting web search to sites.
+func siteQuery(sites []string) string {
+	ops := make([]string, len(sites))
+	for i, site := range sites {
+		ops[i] = "site:" + siteDomain(site)
+	}
+	if len(ops) == 1 {
+		return ops[0]
+	}
+	return "(" + strings.Join(ops, " OR ") + ")"
+}
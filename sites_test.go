@@ -0,0 +1,93 @@
+package goso
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSiteDomain(t *testing.T) {
+	tests := []struct {
+		site   string
+		domain string
+	}{
+		{"stackoverflow", "stackoverflow.com"},
+		{"superuser", "superuser.com"},
+		{"unix", "unix.stackexchange.com"},
+		{"ru.stackoverflow", "ru.stackoverflow.com"},
+		{"meta.superuser", "meta.superuser.com"},
+		{"meta.unix", "meta.unix.stackexchange.com"},
+		{"mathoverflow", "mathoverflow.net"},
+		{"askubuntu.com", "askubuntu.com"},
+		{"math.stackexchange.com", "math.stackexchange.com"},
+	}
+	for _, tt := range tests {
+		if got := siteDomain(tt.site); got != tt.domain {
+			t.Errorf("siteDomain(%q): expected %q, got %q", tt.site, tt.domain, got)
+		}
+		site, ok := siteFromHost(tt.domain)
+		if !ok || site != normalizeSite(tt.site) {
+			t.Errorf("siteFromHost(%q): expected %q, got %q", tt.domain, normalizeSite(tt.site), site)
+		}
+	}
+	for _, host := range []string{"google.com", "stackexchange.com", "api.stackexchange.com", "a.b.stackoverflow.com"} {
+		if site, ok := siteFromHost(host); ok {
+			t.Errorf("siteFromHost(%q): expected no site, got %q", host, site)
+		}
+	}
+	conf := &Config{Sites: []string{"superuser.com", " unix ", "superuser", ""}}
+	if got := fmt.Sprint(conf.sites()); got != "[superuser unix]" {
+		t.Errorf("unexpected sites: %s", got)
+	}
+	if got := siteQuery(conf.sites()); got != "(site:superuser.com OR site:unix.stackexchange.com)" {
+		t.Errorf("unexpected site query: %s", got)
+	}
+}
+
+func TestMultipleSites(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := r.URL.Query().Get("site")
+		domain := siteDomain(site)
+		switch r.URL.Path {
+		case "/search/advanced":
+			// the same question ID on both sites
+			fmt.Fprintf(w, `{"items": [{"question_id": 1, "score": %d, "answer_count": 1, "creation_date": 1700000000,
+				"link": "https://%s/questions/1/q", "title": "Question on %s"}]}`, len(site), domain, site)
+		case "/similar":
+			fmt.Fprint(w, `{"items": []}`)
+		case "/questions/1":
+			fmt.Fprint(w, `{"items": [{"question_id": 1, "tags": ["ssh"]}]}`)
+		case "/questions/1/answers":
+			fmt.Fprintf(w, `{"items": [{"question_id": 1, "answer_id": %d, "score": 1, "body": "<p>%s</p>"}]}`, len(site), site)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	conf := &Config{
+		Query:            "ssh keys",
+		QuestionNum:      5,
+		AnswerNum:        1,
+		Sites:            []string{"superuser", "unix"},
+		StackExchangeAPI: ts.URL,
+		Client:           ts.Client(),
+	}
+	results, err := GetResults(conf, StackExchangeSearcher{}, FetchStackOverflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for i, want := range []struct{ site, answer string }{
+		{"superuser", "https://superuser.com/a/9"},
+		{"unix", "https://unix.stackexchange.com/a/4"},
+	} {
+		res := results[i]
+		if res.Site != want.site || len(res.Answers) != 1 || res.Answers[0].Link != want.answer ||
+			res.Answers[0].Body != "<p>"+want.site+"</p>" {
+			t.Fatalf("unexpected result %d: %+v", i, res)
+		}
+	}
+}
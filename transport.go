@@ -0,0 +1,79 @@
+package goso
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	netUrl "net/url"
+	"os"
+)
+
+// TransportConfig configures http.Transport created by NewTransport.
+type TransportConfig struct {
+	// CAFile is a PEM bundle of certificates trusted in addition to the system ones
+	CAFile string
+	// Insecure disables verification of server certificates
+	Insecure bool
+	// Proxy is URL of http, https or socks5 proxy with optional credentials,
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used if empty
+	Proxy string
+}
+
+// NewTransport returns http.Transport verifying server certificates
+// against system roots and certificates from tc.CAFile.
+func NewTransport(tc *TransportConfig) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if tc.Proxy != "" {
+		u, err := parseProxy(tc.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxy = http.ProxyURL(u)
+	}
+	tlsConf := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: tc.Insecure,
+	}
+	if tc.CAFile != "" {
+		pem, err := os.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed reading CA file: no certificates found in %s", tc.CAFile)
+		}
+		tlsConf.RootCAs = pool
+	}
+	return &http.Transport{
+		Proxy:             proxy,
+		TLSClientConfig:   tlsConf,
+		ForceAttemptHTTP2: true,
+	}, nil
+}
+
+func parseProxy(proxy string) (*netUrl.URL, error) {
+	u, err := netUrl.Parse(proxy)
+	if err != nil {
+		// do not leak credentials from the URL in error message
+		var urlErr *netUrl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed parsing proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q: should be one of [http, https, socks5]", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL has no host")
+	}
+	return u, nil
+}
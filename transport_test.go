@@ -0,0 +1,213 @@
+package goso
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	netUrl "net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	// handshake errors are expected
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func writeCAFile(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func doTLS(t *testing.T, tc *TransportConfig, url string) error {
+	t.Helper()
+	tr, err := NewTransport(tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+	res, err := (&http.Client{Transport: tr}).Get(url)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func TestNewTransportVerifiesCertificates(t *testing.T) {
+	ts := newTLSServer(t)
+	if err := doTLS(t, &TransportConfig{}, ts.URL); err == nil {
+		t.Fatal("expected certificate verification error")
+	}
+}
+
+func TestNewTransportCAFile(t *testing.T) {
+	ts := newTLSServer(t)
+	if err := doTLS(t, &TransportConfig{CAFile: writeCAFile(t, ts)}, ts.URL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewTransportInsecure(t *testing.T) {
+	ts := newTLSServer(t)
+	if err := doTLS(t, &TransportConfig{Insecure: true}, ts.URL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewTransportBadCAFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTransport(&TransportConfig{CAFile: path}); err == nil {
+		t.Fatal("expected error for CA file without certificates")
+	}
+	if _, err := NewTransport(&TransportConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Fatal("expected error for missing CA file")
+	}
+}
+
+func TestNewTransportHTTPProxy(t *testing.T) {
+	var gotURL, gotAuth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		gotAuth = r.Header.Get("Proxy-Authorization")
+		w.Write([]byte("proxied"))
+	}))
+	t.Cleanup(proxy.Close)
+	u, _ := netUrl.Parse(proxy.URL)
+	u.User = netUrl.UserPassword("user", "pass")
+	tr, err := NewTransport(&TransportConfig{Proxy: u.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := (&http.Client{Transport: tr}).Get("http://api.example.invalid/2.3/search?q=go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "proxied" || gotURL != "http://api.example.invalid/2.3/search?q=go" {
+		t.Fatalf("request was not routed through proxy: %q %q", body, gotURL)
+	}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	if gotAuth != want {
+		t.Fatalf("expected proxy credentials %q, got %q", want, gotAuth)
+	}
+}
+
+// serveSOCKS5 accepts a single connection on l, performs SOCKS5 handshake with
+// username/password authentication and connects client to the requested address.
+func serveSOCKS5(l net.Listener, user, pass string, target chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	buf := make([]byte, 512)
+	// greeting: VER NMETHODS METHODS
+	if _, err = io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err = io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 2})
+	// RFC 1929: VER ULEN UNAME PLEN PASSWD
+	io.ReadFull(conn, buf[:2])
+	u := make([]byte, buf[1])
+	io.ReadFull(conn, u)
+	io.ReadFull(conn, buf[:1])
+	p := make([]byte, buf[0])
+	io.ReadFull(conn, p)
+	if string(u) != user || string(p) != pass {
+		conn.Write([]byte{1, 1})
+		return
+	}
+	conn.Write([]byte{1, 0})
+	// request: VER CMD RSV ATYP DST.ADDR DST.PORT
+	io.ReadFull(conn, buf[:4])
+	var host string
+	switch buf[3] {
+	case 1:
+		io.ReadFull(conn, buf[:4])
+		host = net.IP(buf[:4]).String()
+	case 3:
+		io.ReadFull(conn, buf[:1])
+		name := make([]byte, buf[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return
+	}
+	io.ReadFull(conn, buf[:2])
+	addr := net.JoinHostPort(host, strconv.Itoa(int(buf[0])<<8|int(buf[1])))
+	target <- addr
+	upstream, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func TestNewTransportSOCKS5Proxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(ts.Close)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	target := make(chan string, 1)
+	go serveSOCKS5(l, "user", "pass", target)
+	tr, err := NewTransport(&TransportConfig{Proxy: "socks5://user:pass@" + l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+	res, err := (&http.Client{Transport: tr}).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if addr := <-target; addr != ts.Listener.Addr().String() {
+		t.Fatalf("expected proxy to connect to %s, got %s", ts.Listener.Addr(), addr)
+	}
+}
+
+func TestNewTransportBadProxy(t *testing.T) {
+	for _, proxy := range []string{"ftp://127.0.0.1:21", "http://", "http://user:pa ss@127.0.0.1:%zz"} {
+		_, err := NewTransport(&TransportConfig{Proxy: proxy})
+		if err == nil {
+			t.Fatalf("expected error for proxy %q", proxy)
+		}
+		if strings.Contains(err.Error(), "pa ss") {
+			t.Fatalf("proxy credentials leaked in error: %v", err)
+		}
+	}
+}
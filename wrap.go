@@ -0,0 +1,93 @@
+package goso
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// runeWidth returns the number of terminal columns occupied by r.
+func runeWidth(r rune) int {
+	if r == utf8.RuneError || !unicode.IsPrint(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// visibleWidth returns the number of terminal columns occupied by s ignoring escape sequences.
+func visibleWidth(s string) int {
+	var w int
+	for _, r := range ansiPattern.ReplaceAllString(s, "") {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// ScreenLines returns the number of terminal lines s takes when its lines wrap at columns,
+// zero columns disable wrapping. Escape sequences setting colors and styles do not take space.
+func ScreenLines(s string, columns int) int {
+	var n int
+	for _, line := range strings.Split(s, "\n") {
+		if w := visibleWidth(line); columns > 0 && w > columns {
+			n += (w + columns - 1) / columns
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// wrapText breaks lines of s at spaces so that they fit into width columns.
+// Escape sequences do not count towards the width, and styles active at
+// the end of a line are reset and then restored on the next one, so
+// the lines can be safely prefixed. Words longer than width are not broken.
+func wrapText(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	var (
+		sb     strings.Builder
+		active []string
+	)
+	newline := func() {
+		if len(active) > 0 {
+			sb.WriteString(reset)
+		}
+		sb.WriteString("\n")
+		for _, code := range active {
+			sb.WriteString(code)
+		}
+	}
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			newline()
+		}
+		var col int
+		for j, word := range strings.Split(line, " ") {
+			w := visibleWidth(word)
+			if col > 0 && col+1+w > width {
+				newline()
+				col = 0
+			} else if j > 0 {
+				sb.WriteString(" ")
+				col++
+			}
+			sb.WriteString(word)
+			col += w
+			for _, code := range ansiPattern.FindAllString(word, -1) {
+				if code == reset {
+					active = active[:0]
+				} else {
+					active = append(active, code)
+				}
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,110 @@
+package goso
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVisibleWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"hello", 5},
+		{bold + "hello" + reset, 5},
+		{"日本語", 6},
+		{"é", 1},
+		{"ｈｉ", 4},
+	}
+	for _, tt := range tests {
+		if got := visibleWidth(tt.s); got != tt.want {
+			t.Errorf("visibleWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestScreenLines(t *testing.T) {
+	tests := []struct {
+		s       string
+		columns int
+		want    int
+	}{
+		{"", 10, 1},
+		{"one\ntwo\n", 10, 3},
+		{"0123456789", 10, 1},
+		{"0123456789a", 10, 2},
+		{yellow + "0123456789" + reset, 10, 1},
+		{"日本語日本", 10, 1},
+		{"日本語日本語", 10, 2},
+		{strings.Repeat("x", 25), 0, 1},
+	}
+	for _, tt := range tests {
+		if got := ScreenLines(tt.s, tt.columns); got != tt.want {
+			t.Errorf("ScreenLines(%q, %d) = %d, want %d", tt.s, tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		width int
+		want  string
+	}{
+		{
+			name:  "plain",
+			s:     "the quick brown fox jumps over the lazy dog",
+			width: 10,
+			want:  "the quick\nbrown fox\njumps over\nthe lazy\ndog",
+		},
+		{
+			name:  "long word",
+			s:     "see https://example.com/very/long/url now",
+			width: 10,
+			want:  "see\nhttps://example.com/very/long/url\nnow",
+		},
+		{
+			name:  "escape sequences",
+			s:     "one " + bold + "two three" + reset + " four",
+			width: 8,
+			want:  "one " + bold + "two" + reset + "\n" + bold + "three" + reset + "\nfour",
+		},
+		{
+			name:  "wide characters",
+			s:     "日本語 日本語 日本語",
+			width: 13,
+			want:  "日本語 日本語\n日本語",
+		},
+		{
+			name:  "disabled",
+			s:     "the quick brown fox",
+			width: 0,
+			want:  "the quick brown fox",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(tt.s, tt.width); got != tt.want {
+				t.Fatalf("\ngot:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWrapped(t *testing.T) {
+	rd := &renderer{plain: true, width: 20}
+	var sb strings.Builder
+	body := "<ul><li>a list item that needs wrapping</li></ul>" +
+		"<blockquote><p>a quote that needs wrapping too</p></blockquote>" +
+		"<pre><code>code that is never wrapped by renderer</code></pre>"
+	if err := rd.renderBody(body, &sb); err != nil {
+		t.Fatal(err)
+	}
+	want := " - a list item that\n   needs wrapping\n\n" +
+		"> a quote that needs\n> wrapping too\n\n" +
+		"code that is never wrapped by renderer\n"
+	if sb.String() != want {
+		t.Fatalf("\ngot:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}